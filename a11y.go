@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// unlabeledInputTypes are input types that do not need an accessible label
+var unlabeledInputTypes = map[string]bool{
+	"hidden": true,
+	"submit": true,
+	"reset":  true,
+	"button": true,
+	"image":  true,
+}
+
+// extractARIA counts elements by their ARIA role, such as navigation, main and banner.
+// Only the first token of a role attribute is counted, as that is the role browsers apply
+func extractARIA(n *html.Node) map[string]int {
+	roles := make(map[string]int)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if fields := strings.Fields(strings.ToLower(getAttr(n, "role"))); len(fields) > 0 {
+				roles[fields[0]]++
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return roles
+}
+
+// extractA11yWarnings reports images without alt text and form controls without an
+// accessible label (a <label>, aria-label, aria-labelledby or title)
+func extractA11yWarnings(n *html.Node) []string {
+	// Collect the ids referenced by <label for> first
+	labeled := make(map[string]bool)
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "label" {
+			if id := getAttr(n, "for"); id != "" {
+				labeled[id] = true
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+
+	var warnings []string
+	var walk func(n *html.Node, inLabel bool)
+	walk = func(n *html.Node, inLabel bool) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "label":
+				inLabel = true
+			case "img":
+				if !hasAttr(n, "alt") {
+					warnings = append(warnings, fmt.Sprintf("image missing alt text: %s", getAttr(n, "src")))
+				}
+			case "input", "select", "textarea":
+				if n.Data == "input" && unlabeledInputTypes[strings.ToLower(getAttr(n, "type"))] {
+					break
+				}
+				if !inLabel && !labeled[getAttr(n, "id")] && !hasAccessibleName(n) {
+					warnings = append(warnings, fmt.Sprintf("unlabeled form control: <%s name=%q>", n.Data, getAttr(n, "name")))
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, inLabel)
+		}
+	}
+	walk(n, false)
+	return warnings
+}
+
+// hasAccessibleName reports whether an element is labeled through ARIA or title attributes
+func hasAccessibleName(n *html.Node) bool {
+	for _, key := range []string{"aria-label", "aria-labelledby", "title"} {
+		if strings.TrimSpace(getAttr(n, key)) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAttr reports whether the element has the named attribute, even if empty
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Asset is an external script or stylesheet referenced by a page
+type Asset struct {
+	URL  string
+	Type string // "script" or "stylesheet"
+
+	// Integrity and CrossOrigin are the element's Subresource Integrity attributes
+	Integrity   string
+	CrossOrigin string
+}
+
+// resourceCounts holds the element counts gathered by extractAssets. Stylesheets include
+// inline <style> elements as well as linked ones
+type resourceCounts struct {
+	scripts, stylesheets, images, iframes int
+}
+
+// extractAssets returns the absolute URLs of external <script src> and
+// <link rel="stylesheet"> elements, deduplicated in document order, and counts the
+// page's scripts, stylesheets, images and iframes in the same pass
+func extractAssets(n *html.Node, base *url.URL) ([]Asset, resourceCounts) {
+	var (
+		assets []Asset
+		counts resourceCounts
+	)
+	seen := make(map[string]bool)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			var asset Asset
+			switch {
+			case n.Data == "script":
+				counts.scripts++
+				asset = Asset{URL: resolveURL(base, getAttr(n, "src")), Type: "script"}
+			case n.Data == "link" && hasRel(n, "stylesheet"):
+				counts.stylesheets++
+				asset = Asset{URL: resolveURL(base, getAttr(n, "href")), Type: "stylesheet"}
+			case n.Data == "style":
+				counts.stylesheets++
+			case n.Data == "img":
+				counts.images++
+			case n.Data == "iframe":
+				counts.iframes++
+			}
+			if asset.URL != "" && !seen[asset.URL] {
+				seen[asset.URL] = true
+				asset.Integrity = strings.TrimSpace(getAttr(n, "integrity"))
+				asset.CrossOrigin = strings.TrimSpace(getAttr(n, "crossorigin"))
+				assets = append(assets, asset)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return assets, counts
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// maxTitleLength is the title length above which search engines usually truncate it
+const maxTitleLength = 60
+
+// AuditPage runs SEO and security checks over an extracted page and returns its findings
+func AuditPage(info *PageInfo) []string {
+	var findings []string
+
+	// SEO checks
+	switch {
+	case info.Title == "":
+		findings = append(findings, "missing title")
+	case len([]rune(info.Title)) > maxTitleLength:
+		findings = append(findings, fmt.Sprintf("title longer than %d characters", maxTitleLength))
+	}
+	if info.Meta["description"] == "" {
+		findings = append(findings, "missing meta description")
+	}
+	if info.Canonical == "" {
+		findings = append(findings, "missing canonical link")
+	}
+
+	// Standards checks
+	switch {
+	case info.Doctype == "":
+		findings = append(findings, "missing doctype")
+	case info.QuirksMode:
+		findings = append(findings, fmt.Sprintf("doctype %s triggers quirks mode", info.Doctype))
+	case info.Doctype != html5Doctype:
+		findings = append(findings, fmt.Sprintf("legacy doctype %s", info.Doctype))
+	}
+
+	// Security checks
+	findings = append(findings, cspWeaknesses(info.CSP)...)
+	findings = append(findings, integrityGaps(info)...)
+	for _, failure := range info.IntegrityFailures {
+		findings = append(findings, "integrity check failed for "+failure)
+	}
+
+	return findings
+}
+
+// integrityGaps reports cross-origin scripts and stylesheets loaded without an integrity attribute
+func integrityGaps(info *PageInfo) []string {
+	page, err := url.Parse(info.URL)
+	if err != nil {
+		return nil
+	}
+	var gaps []string
+	for _, asset := range info.Assets {
+		u, err := url.Parse(asset.URL)
+		if err != nil || asset.Integrity != "" || strings.EqualFold(u.Host, page.Host) {
+			continue
+		}
+		gaps = append(gaps, fmt.Sprintf("cross-origin %s %s has no integrity attribute", asset.Type, asset.URL))
+	}
+	return gaps
+}
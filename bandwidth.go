@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"golang.org/x/time/rate"
+)
+
+// WithBandwidthLimit caps how fast response bodies are read, in bytes per second, across
+// all requests made by the client. It applies in addition to the request rate limit,
+// so large pages cannot exceed the budget. Reads block until enough tokens are available;
+// bursts of up to one second's worth of bytes are allowed. A limit of zero or less leaves
+// bandwidth unlimited
+func WithBandwidthLimit(bytesPerSecond int) ClientOption {
+	return func(c *HTTPClient) {
+		if bytesPerSecond <= 0 {
+			c.bandwidth = nil
+			return
+		}
+		c.bandwidth = rate.NewLimiter(rate.Limit(bytesPerSecond), bytesPerSecond)
+	}
+}
+
+// throttledBody limits reads from a response body with the client's bandwidth limiter
+type throttledBody struct {
+	io.ReadCloser
+	ctx     context.Context
+	limiter *rate.Limiter
+}
+
+// throttleBody wraps the response body so reading it consumes bandwidth tokens
+func (c *HTTPClient) throttleBody(resp *http.Response) {
+	if c.bandwidth == nil || resp.Body == nil || resp.Body == http.NoBody {
+		return
+	}
+	resp.Body = &throttledBody{ReadCloser: resp.Body, ctx: resp.Request.Context(), limiter: c.bandwidth}
+}
+
+// Read reads at most one burst and then waits for the bytes read to be paid for
+func (b *throttledBody) Read(p []byte) (int, error) {
+	if burst := b.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		if waitErr := b.limiter.WaitN(b.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
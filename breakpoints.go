@@ -0,0 +1,47 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	// cssComment matches /* ... */ comments, which may hide @media rules
+	cssComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	// mediaPrelude captures the query list of an @media rule
+	mediaPrelude = regexp.MustCompile(`(?i)@media\s+([^{;]+)\{`)
+	// widthFeature matches (min-width: 768px) style features
+	widthFeature = regexp.MustCompile(`(?i)\(\s*((?:min-|max-)?(?:device-)?width)\s*:\s*([^)]+?)\s*\)`)
+	// widthRange matches (width >= 768px) range syntax features
+	widthRange = regexp.MustCompile(`(?i)\(\s*(width)\s*(<=|>=|<|>|=)\s*([^)]+?)\s*\)`)
+)
+
+// extractBreakpoints returns the distinct width conditions of the @media rules in the
+// document's <style> blocks, such as "max-width: 768px" or "width >= 600px", lowercased and
+// in the order they first appear. Only inline styles are scanned, not linked stylesheets
+func extractBreakpoints(n *html.Node) []string {
+	breakpoints := newOrderedSet()
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "style" {
+			css := cssComment.ReplaceAllString(nodeText(n), "")
+			for _, m := range mediaPrelude.FindAllStringSubmatch(css, -1) {
+				query := strings.ToLower(m[1])
+				for _, f := range widthFeature.FindAllStringSubmatch(query, -1) {
+					breakpoints.add(f[1] + ": " + f[2])
+				}
+				for _, f := range widthRange.FindAllStringSubmatch(query, -1) {
+					breakpoints.add(f[1] + " " + f[2] + " " + f[3])
+				}
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return breakpoints.list()
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// ErrInteractionNotFound is returned in replay mode when no recorded interaction matches a request
+var ErrInteractionNotFound = errors.New("no recorded interaction matches request")
+
+// CassetteMode selects whether a CassetteTransport records or replays interactions
+type CassetteMode int
+
+const (
+	// ReplayMode serves responses from the cassette file without touching the network
+	ReplayMode CassetteMode = iota
+	// RecordMode sends requests to the network and saves every interaction to the cassette
+	RecordMode
+)
+
+// redactedValue replaces the values of redacted headers in a cassette
+const redactedValue = "[REDACTED]"
+
+// defaultRedactedHeaders are the credential-bearing headers left out of cassettes unless
+// SetRedactedHeaders says otherwise
+var defaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// Interaction is one recorded request/response pair
+type Interaction struct {
+	Method          string
+	URL             string
+	RequestHeaders  http.Header
+	StatusCode      int
+	ResponseHeaders http.Header
+	Body            []byte
+}
+
+// CassetteTransport is a VCR-style http.RoundTripper that records real interactions to a
+// file once and replays them afterwards, making tests hermetic. Requests are matched on
+// method and URL plus any headers listed in matchHeaders. Install it with WithTransport.
+// Credential headers are redacted in recordings, as cassettes are usually committed as
+// test fixtures; see SetRedactedHeaders
+type CassetteTransport struct {
+	path         string
+	mode         CassetteMode
+	next         http.RoundTripper
+	matchHeaders []string
+	redact       []string
+
+	mu           sync.Mutex
+	interactions []Interaction
+	replayed     map[int]bool
+}
+
+// NewCassetteTransport creates a cassette transport backed by the file at path.
+// In replay mode the cassette is loaded immediately; in record mode requests are sent
+// through next (http.DefaultTransport if nil) and the file is rewritten after each one
+func NewCassetteTransport(path string, mode CassetteMode, next http.RoundTripper, matchHeaders ...string) (*CassetteTransport, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := &CassetteTransport{
+		path:         path,
+		mode:         mode,
+		next:         next,
+		matchHeaders: matchHeaders,
+		redact:       defaultRedactedHeaders,
+		replayed:     make(map[int]bool),
+	}
+
+	if mode == ReplayMode {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cassette: %w", err)
+		}
+		if err := json.Unmarshal(data, &t.interactions); err != nil {
+			return nil, fmt.Errorf("failed to decode cassette: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// SetRedactedHeaders replaces the request and response headers whose values are redacted
+// when recording. By default Authorization, Proxy-Authorization, Cookie and Set-Cookie
+// are redacted; calling it with no headers records every header verbatim. Redacted
+// headers listed in matchHeaders match any value on replay
+func (t *CassetteTransport) SetRedactedHeaders(headers ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.redact = headers
+}
+
+// RoundTrip records or replays a single request
+func (t *CassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.mode == ReplayMode {
+		return t.replay(req)
+	}
+	return t.record(req)
+}
+
+// replay serves the first unused matching interaction, or the last match if all were used
+func (t *CassetteTransport) replay(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	found := -1
+	for i, in := range t.interactions {
+		if !t.matches(in, req) {
+			continue
+		}
+		found = i
+		if !t.replayed[i] {
+			break
+		}
+	}
+	if found < 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrInteractionNotFound, req.Method, req.URL)
+	}
+	t.replayed[found] = true
+
+	in := t.interactions[found]
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.StatusCode, http.StatusText(in.StatusCode)),
+		StatusCode:    in.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        in.ResponseHeaders.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(in.Body)),
+		ContentLength: int64(len(in.Body)),
+		Request:       req,
+	}, nil
+}
+
+// record sends the request and appends the interaction to the cassette file
+func (t *CassetteTransport) record(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.interactions = append(t.interactions, Interaction{
+		Method:          req.Method,
+		URL:             req.URL.String(),
+		RequestHeaders:  t.redacted(req.Header),
+		StatusCode:      resp.StatusCode,
+		ResponseHeaders: t.redacted(resp.Header),
+		Body:            body,
+	})
+	if err := t.save(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// matches reports whether a recorded interaction corresponds to req
+func (t *CassetteTransport) matches(in Interaction, req *http.Request) bool {
+	if in.Method != req.Method || in.URL != req.URL.String() {
+		return false
+	}
+	for _, h := range t.matchHeaders {
+		recorded := in.RequestHeaders.Get(h)
+		if recorded != redactedValue && recorded != req.Header.Get(h) {
+			return false
+		}
+	}
+	return true
+}
+
+// redacted returns a copy of header with the values of redacted headers replaced
+func (t *CassetteTransport) redacted(header http.Header) http.Header {
+	clone := header.Clone()
+	for _, h := range t.redact {
+		if clone.Get(h) != "" {
+			clone.Set(h, redactedValue)
+		}
+	}
+	return clone
+}
+
+// save writes all recorded interactions to the cassette file
+func (t *CassetteTransport) save() error {
+	data, err := json.MarshalIndent(t.interactions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	if err := os.WriteFile(t.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
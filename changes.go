@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// cachedValidators are the conditional GET validators last seen for a URL
+type cachedValidators struct {
+	etag         string
+	lastModified string
+	contentHash  string
+}
+
+// validatorCache remembers validators per URL so that repeated checks can use conditional GETs
+type validatorCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedValidators
+}
+
+// newValidatorCache creates an empty validator cache
+func newValidatorCache() *validatorCache {
+	return &validatorCache{entries: make(map[string]cachedValidators)}
+}
+
+func (vc *validatorCache) get(url string) (cachedValidators, bool) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	v, ok := vc.entries[url]
+	return v, ok
+}
+
+func (vc *validatorCache) put(url string, v cachedValidators) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	vc.entries[url] = v
+}
+
+// HasChanged reports whether the content at url differs from previousContentHash.
+// When the client has validators (ETag/Last-Modified) for the same content it sends a
+// conditional GET, and a 304 Not Modified returns false without downloading the body.
+// newHash is the BLAKE2b-256 content hash of the current content
+func (c *HTTPClient) HasChanged(ctx context.Context, url, previousContentHash string) (changed bool, newHash string, err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Only send validators that belong to the content the caller already has
+	if v, ok := c.validators.get(url); ok && previousContentHash != "" && v.contentHash == previousContentHash {
+		if v.etag != "" {
+			req.Header.Set("If-None-Match", v.etag)
+		}
+		if v.lastModified != "" {
+			req.Header.Set("If-Modified-Since", v.lastModified)
+		}
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return false, previousContentHash, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return false, "", fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to read response body: %w", err)
+	}
+	newHash = hashContent(body)
+
+	c.validators.put(url, cachedValidators{
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		contentHash:  newHash,
+	})
+
+	return newHash != previousContentHash, newHash, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+const (
+	// chunkWindow is the number of trailing bytes the rolling hash covers
+	chunkWindow = 48
+	// chunkPrime is the multiplier of the polynomial rolling hash
+	chunkPrime = 1099511628211
+	// minChunkAvgSize is the smallest average chunk size accepted by ChunkContent
+	minChunkAvgSize = 64
+)
+
+// ErrChunkSize is returned by ChunkContent for an average chunk size below minChunkAvgSize
+var ErrChunkSize = errors.New("average chunk size too small")
+
+// Chunk is a content-defined piece of a larger body
+type Chunk struct {
+	Offset int
+	Length int
+	Hash   string // BLAKE2b-256, hex-encoded
+}
+
+// ChunkContent splits r into content-defined chunks and hashes each with BLAKE2b-256.
+// Because boundaries depend only on nearby content, an insertion or deletion changes just
+// the chunks around it, so similar pages share most of their chunks.
+//
+// A Rabin-Karp polynomial hash with multiplier chunkPrime (mod 2^64) rolls over the last
+// 48 bytes, and a boundary is placed after any byte where the hash's low bits are all set.
+// The number of low bits is log2 of avgSize rounded up to a power of two, so a boundary
+// occurs with probability 1/avgSize per byte. Chunks are at least max(avgSize/4, 48) and at
+// most 4*avgSize bytes, so a chunk's size is roughly the minimum plus a geometrically
+// distributed length with mean the rounded avgSize, cut off at the maximum; for avgSize
+// 4096 that averages about 5KB. Only the current chunk is held in memory
+func ChunkContent(r io.Reader, avgSize int) ([]Chunk, error) {
+	if avgSize < minChunkAvgSize {
+		return nil, fmt.Errorf("%w: got %d, want at least %d", ErrChunkSize, avgSize, minChunkAvgSize)
+	}
+	mask := uint64(1)<<bits.Len(uint(avgSize-1)) - 1
+	minSize, maxSize := max(avgSize/4, chunkWindow), avgSize*4
+
+	// outFactor removes the byte leaving the window: chunkPrime^chunkWindow
+	outFactor := uint64(1)
+	for i := 0; i < chunkWindow; i++ {
+		outFactor *= chunkPrime
+	}
+
+	var (
+		chunks []Chunk
+		window [chunkWindow]byte
+		buf    = make([]byte, 0, maxSize)
+		hash   uint64
+		offset int
+	)
+	emit := func() {
+		chunks = append(chunks, Chunk{Offset: offset, Length: len(buf), Hash: hashContent(buf)})
+		offset += len(buf)
+		buf = buf[:0]
+		window = [chunkWindow]byte{}
+		hash = 0
+	}
+
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read content: %w", err)
+		}
+
+		// Roll the hash: shift in the new byte and drop the one leaving the window
+		i := len(buf) % chunkWindow
+		hash = hash*chunkPrime + uint64(b) - uint64(window[i])*outFactor
+		window[i] = b
+		buf = append(buf, b)
+
+		if len(buf) >= minSize && (hash&mask == mask || len(buf) >= maxSize) {
+			emit()
+		}
+	}
+	if len(buf) > 0 {
+		emit()
+	}
+	return chunks, nil
+}
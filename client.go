@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+)
+
+// ClientOption configures an HTTPClient
+type ClientOption func(*HTTPClient)
+
+// ErrUnsupportedTransport is returned by every request of a client whose transport options
+// cannot be applied to the custom transport installed with WithTransport
+var ErrUnsupportedTransport = errors.New("transport options require an *http.Transport")
+
+// transportOptions holds the options applied to the client's *http.Transport once all
+// client options have run, so they take effect whatever the option order
+type transportOptions struct {
+	rootCAs        *x509.CertPool
+	verifyPeer     func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error
+	maxHeaderBytes int64
+}
+
+// isSet reports whether any transport option was given
+func (o transportOptions) isSet() bool {
+	return o.rootCAs != nil || o.verifyPeer != nil || o.maxHeaderBytes != 0
+}
+
+// apply sets the options on t
+func (o transportOptions) apply(t *http.Transport) {
+	if o.rootCAs != nil {
+		tlsConfig(t).RootCAs = o.rootCAs
+	}
+	if o.verifyPeer != nil {
+		tlsConfig(t).VerifyPeerCertificate = o.verifyPeer
+	}
+	if o.maxHeaderBytes != 0 {
+		t.MaxResponseHeaderBytes = o.maxHeaderBytes
+	}
+}
+
+// WithTransport replaces the client's transport with rt, for example a CassetteTransport.
+// If rt is an *http.Transport, the client uses a clone of it with WithRootCAs,
+// WithVerifyPeerCertificate, WithMaxResponseHeaderBytes and WithDoH applied. Any other
+// RoundTripper is used as is: WithDoH has no effect on it, and because the TLS and header
+// limits cannot be enforced, combining them with it makes every request fail with
+// ErrUnsupportedTransport rather than silently skipping the checks
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *HTTPClient) {
+		c.client.Transport = rt
+	}
+}
+
+// WithMaxResponseHeaderBytes caps the size of the response headers the client will read,
+// protecting against servers that send huge headers to exhaust memory. Responses over the
+// limit fail with an error. Zero keeps Go's default of 1MB. See WithTransport for custom
+// transports
+func WithMaxResponseHeaderBytes(n int64) ClientOption {
+	return func(c *HTTPClient) {
+		c.transportOpts.maxHeaderBytes = n
+	}
+}
+
+// setupTransport applies the transport options to the transport the client will use,
+// cloning a custom *http.Transport so the caller's copy is left untouched
+func (c *HTTPClient) setupTransport() {
+	if t, ok := c.client.Transport.(*http.Transport); ok && t != c.transport {
+		c.transport = t.Clone()
+		c.client.Transport = c.transport
+	}
+	if c.client.Transport != c.transport {
+		if c.transportOpts.isSet() {
+			c.transportErr = fmt.Errorf("%w, got %T", ErrUnsupportedTransport, c.client.Transport)
+		}
+		return
+	}
+	c.transportOpts.apply(c.transport)
+}
+
+// WithCookieJar stores cookies set by responses in jar and sends them on later requests,
+// keeping login sessions alive across requests
+func WithCookieJar(jar http.CookieJar) ClientOption {
+	return func(c *HTTPClient) {
+		c.client.Jar = jar
+	}
+}
+
+// RequestHook modifies an outgoing request before it is sent
+type RequestHook func(req *http.Request)
+
+// WithRequestHook adds a hook that runs on every request, after user-agent rotation.
+// Hooks run in the order they were added
+func WithRequestHook(hook RequestHook) ClientOption {
+	return func(c *HTTPClient) {
+		c.hooks = append(c.hooks, hook)
+	}
+}
+
+// UARotation selects how WithUserAgents picks the next user agent
+type UARotation int
+
+const (
+	// RoundRobinUA cycles through the user agents in order
+	RoundRobinUA UARotation = iota
+	// RandomUA picks a user agent at random for each request
+	RandomUA
+)
+
+// WithUserAgents sets a different User-Agent from agents on every request.
+// Rotation is applied before any request hooks, so hooks can still override it.
+// Aggressive user-agent rotation may violate some sites' terms of service
+func WithUserAgents(agents []string, mode UARotation) ClientOption {
+	return func(c *HTTPClient) {
+		if len(agents) == 0 {
+			c.userAgents = nil
+			return
+		}
+		c.userAgents = &uaRotator{agents: append([]string(nil), agents...), mode: mode}
+	}
+}
+
+// uaRotator picks user agents for WithUserAgents
+type uaRotator struct {
+	agents []string
+	mode   UARotation
+	next   atomic.Uint64
+}
+
+// pick returns the user agent for the next request
+func (r *uaRotator) pick() string {
+	if r.mode == RandomUA {
+		return r.agents[rand.IntN(len(r.agents))]
+	}
+	i := r.next.Add(1) - 1
+	return r.agents[i%uint64(len(r.agents))]
+}
+
+// RequestMetrics describes a single HTTP round trip made by the client
+type RequestMetrics struct {
+	URL        string
+	StatusCode int
+	Duration   time.Duration
+	ConnReused bool
+	Err        error
+}
+
+// WithMetricsCallback calls fn after every attempt, including retries,
+// with timing and connection reuse information
+func WithMetricsCallback(fn func(RequestMetrics)) ClientOption {
+	return func(c *HTTPClient) {
+		c.metrics = fn
+	}
+}
+
+// send performs one attempt of req, reporting metrics if a callback is configured
+func (c *HTTPClient) send(req *http.Request) (*http.Response, error) {
+	// Start a fresh redirect trace for each attempt
+	t := redirectTraceFrom(req.Context())
+	if t == nil {
+		t = &redirectTrace{}
+		req = req.WithContext(withRedirectTrace(req.Context(), t))
+	}
+	t.reset(time.Now())
+
+	if c.transportErr != nil {
+		return nil, c.transportErr
+	}
+	if c.metrics == nil {
+		return c.client.Do(req)
+	}
+
+	var reused atomic.Bool
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			reused.Store(info.Reused)
+		},
+	}
+	start := time.Now()
+	resp, err := c.client.Do(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+
+	m := RequestMetrics{
+		URL:        req.URL.String(),
+		Duration:   time.Since(start),
+		ConnReused: reused.Load(),
+		Err:        err,
+	}
+	if resp != nil {
+		m.StatusCode = resp.StatusCode
+	}
+	c.metrics(m)
+	return resp, err
+}
+
+// RequestOption configures a single request made through HTTPClient
+type RequestOption func(*requestConfig)
+
+// requestConfig holds per-request settings
+type requestConfig struct {
+	gzipBody bool
+}
+
+// WithGzipBody compresses the request body with gzip and sets Content-Encoding: gzip.
+// Only use it with servers that accept compressed request bodies
+func WithGzipBody() RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.gzipBody = true
+	}
+}
+
+// Do performs a rate-limited HTTP request bound to the request's context
+func (c *HTTPClient) Do(req *http.Request, opts ...RequestOption) (*http.Response, error) {
+	if isWebSocketURL(req) {
+		return nil, fmt.Errorf("%w: %s", ErrWebSocketEndpoint, req.URL)
+	}
+
+	var cfg requestConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	// Apply user-agent rotation first, then the per-request header hooks
+	if c.userAgents != nil {
+		req.Header.Set("User-Agent", c.userAgents.pick())
+	}
+	for _, hook := range c.hooks {
+		hook(req)
+	}
+
+	if cfg.gzipBody {
+		if err := gzipRequestBody(req); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := c.roundTrip(req)
+	// Middleware may short-circuit with a response built from scratch
+	if resp != nil && resp.Request == nil {
+		resp.Request = req
+	}
+	if err != nil {
+		return resp, err
+	}
+	c.throttleBody(resp)
+	return resp, nil
+}
+
+// Post performs a rate-limited HTTP POST request
+func (c *HTTPClient) Post(ctx context.Context, url, contentType string, body io.Reader, opts ...RequestOption) (*http.Response, error) {
+	// Create request with context
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	return c.Do(req, opts...)
+}
+
+// gzipRequestBody replaces the request body with its gzip-compressed form.
+// The compressed body is buffered so the request can be replayed on redirects
+func gzipRequestBody(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	defer req.Body.Close()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := io.Copy(zw, req.Body); err != nil {
+		return fmt.Errorf("failed to compress request body: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress request body: %w", err)
+	}
+
+	compressed := buf.Bytes()
+	req.Body = io.NopCloser(bytes.NewReader(compressed))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(compressed)), nil
+	}
+	req.ContentLength = int64(len(compressed))
+	req.Header.Set("Content-Encoding", "gzip")
+	return nil
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http/cookiejar"
+	"net/http/httptrace"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// ErrFetchTimeout is recorded when a single fetch exceeds the crawler's per-fetch timeout
+var ErrFetchTimeout = errors.New("fetch timed out")
+
+// CrawlFailure records a URL that could not be crawled
+type CrawlFailure struct {
+	URL     string
+	Err     error
+	Timeout bool
+}
+
+// minRequestsForReuseWarning is the number of requests needed before low connection reuse is reported
+const minRequestsForReuseWarning = 10
+
+// CrawlResult summarizes a crawl. The pages themselves are written to the crawler's ResultStore
+type CrawlResult struct {
+	Pages    int
+	Failures []CrawlFailure
+
+	// Duplicates counts pages collapsed into an earlier page by WithContentDedup
+	Duplicates int
+
+	// Requests counts the HTTP round trips made, including redirects and retries,
+	// and ReusedConns how many of them reused an existing connection
+	Requests    int
+	ReusedConns int
+}
+
+// ConnReuseRatio returns the fraction of requests that reused an existing connection
+func (r *CrawlResult) ConnReuseRatio() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.ReusedConns) / float64(r.Requests)
+}
+
+// Crawler fetches many pages concurrently through a shared rate-limited client
+type Crawler struct {
+	client       *HTTPClient
+	crypto       *CryptoUtils
+	workers      int
+	pool         *WorkerPool
+	fetchTimeout time.Duration
+	fetchOpts    FetchOptions
+	minReuse     float64
+	store        ResultStore
+	onProgress   func(CrawlProgress)
+	tracker      crawlTracker
+	maxDepth     int
+	urlFilter    func(url string) bool
+	crawlTimeout time.Duration
+	iframes      bool
+	login        LoginFunc
+	maxPerHost   int
+	dedup        bool
+}
+
+// CrawlerOption configures a Crawler
+type CrawlerOption func(*Crawler)
+
+// WithFetchTimeout sets a watchdog for each individual fetch.
+// A fetch running longer than d is cancelled and recorded as a timeout failure,
+// freeing its worker for the next URL. d should be shorter than the crawl context
+func WithFetchTimeout(d time.Duration) CrawlerOption {
+	return func(c *Crawler) {
+		c.fetchTimeout = d
+	}
+}
+
+// WithCrawlTimeout bounds the duration of a whole crawl. When d elapses every worker stops,
+// in-flight requests and body reads are aborted and Crawl returns context.DeadlineExceeded
+// with the partial result, exactly as if the caller's context had expired
+func WithCrawlTimeout(d time.Duration) CrawlerOption {
+	return func(c *Crawler) {
+		c.crawlTimeout = d
+	}
+}
+
+// WithWorkerPool makes the crawler take a slot from a shared pool for every fetch,
+// bounding total parallelism across all crawlers that use the same pool
+func WithWorkerPool(pool *WorkerPool) CrawlerOption {
+	return func(c *Crawler) {
+		c.pool = pool
+	}
+}
+
+// WithFetchOptions sets what the crawler reads and extracts from each page
+func WithFetchOptions(opts FetchOptions) CrawlerOption {
+	return func(c *Crawler) {
+		c.fetchOpts = opts
+	}
+}
+
+// WithReuseWarning logs a warning after a crawl whose connection reuse ratio is below
+// threshold, which usually means keep-alives are disabled or idle connection limits are too low.
+// The warning is off by default, as broad crawls across many hosts reuse few connections by
+// nature; CrawlResult.ConnReuseRatio reports the ratio either way
+func WithReuseWarning(threshold float64) CrawlerOption {
+	return func(c *Crawler) {
+		c.minReuse = threshold
+	}
+}
+
+// WithResultStore makes the crawler write each completed page to store.
+// Without it, pages are kept in a MemoryStore available from Store
+func WithResultStore(store ResultStore) CrawlerOption {
+	return func(c *Crawler) {
+		c.store = store
+	}
+}
+
+// WithMaxDepth makes the crawler follow links discovered on crawled pages, up to depth
+// links away from the seed URLs. The default of zero crawls only the seeds
+func WithMaxDepth(depth int) CrawlerOption {
+	return func(c *Crawler) {
+		c.maxDepth = depth
+	}
+}
+
+// WithMaxPagesPerHost caps how many pages are crawled from any single host, seeds included,
+// so one large site cannot consume a broad crawl's budget. Once a host reaches the cap its
+// further URLs are dropped while other hosts keep being crawled. Zero means no cap
+func WithMaxPagesPerHost(n int) CrawlerOption {
+	return func(c *Crawler) {
+		c.maxPerHost = n
+	}
+}
+
+// WithURLFilter restricts which discovered links are followed to those accepted by filter.
+// Rejected links are still recorded in PageInfo.Links, and seed URLs are always crawled
+func WithURLFilter(filter func(url string) bool) CrawlerOption {
+	return func(c *Crawler) {
+		c.urlFilter = filter
+	}
+}
+
+// WithURLPattern follows only discovered links whose path matches re, such as ^/blog/
+func WithURLPattern(re *regexp.Regexp) CrawlerOption {
+	return WithURLFilter(func(rawURL string) bool {
+		u, err := url.Parse(rawURL)
+		return err == nil && re.MatchString(u.Path)
+	})
+}
+
+// WithFollowIframes also follows iframe sources on the same host as the page embedding them,
+// subject to the same depth limit and URL filter as links
+func WithFollowIframes() CrawlerOption {
+	return func(c *Crawler) {
+		c.iframes = true
+	}
+}
+
+// LoginFunc authenticates the crawler's client before a crawl, typically by fetching the
+// login page, reading its form with ParseForms to pick up CSRF tokens, and submitting the
+// credentials with PostForm. Cookies it receives are kept in the client's cookie jar
+type LoginFunc func(ctx context.Context, client *HTTPClient) error
+
+// WithLogin runs login at the start of every crawl, before any page is fetched, so pages
+// behind a login are crawled with the resulting session. If the client has no cookie jar,
+// one is installed on it. A failed login aborts the crawl
+func WithLogin(login LoginFunc) CrawlerOption {
+	return func(c *Crawler) {
+		c.login = login
+	}
+}
+
+// NewCrawler creates a crawler that runs the given number of workers
+func NewCrawler(client *HTTPClient, crypto *CryptoUtils, workers int, opts ...CrawlerOption) *Crawler {
+	if workers < 1 {
+		workers = 1
+	}
+	c := &Crawler{
+		client:  client,
+		crypto:  crypto,
+		workers: workers,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.store == nil {
+		c.store = NewMemoryStore()
+	}
+	return c
+}
+
+// Store returns the store that receives the crawled pages
+func (c *Crawler) Store() ResultStore {
+	return c.store
+}
+
+// Crawl fetches every URL, writing each page to the result store and collecting failures.
+// With WithMaxDepth, links discovered on crawled pages are followed as well; every URL is
+// crawled at most once. Every request and body read derives from ctx, so cancelling it
+// promptly aborts in-flight fetches and stops all workers; ctx.Err() is then returned
+// with the partial result
+func (c *Crawler) Crawl(ctx context.Context, urls []string) (*CrawlResult, error) {
+	if c.crawlTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.crawlTimeout)
+		defer cancel()
+	}
+
+	if c.login != nil {
+		if err := c.authenticate(ctx); err != nil {
+			return &CrawlResult{}, err
+		}
+	}
+
+	result := &CrawlResult{}
+	queue := newFrontier(c.maxPerHost)
+	var index *contentIndex
+	if c.dedup {
+		index = newContentIndex()
+	}
+	c.tracker.reset()
+
+	// Stop handing out URLs as soon as the crawl is cancelled
+	stop := context.AfterFunc(ctx, queue.close)
+	defer stop()
+
+	for _, url := range urls {
+		if queue.push(crawlJob{url: url}) {
+			c.tracker.add(1)
+		}
+	}
+
+	// Count connection reuse across every request made by this crawl
+	var requests, reused atomic.Int64
+	traceCtx := httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			requests.Add(1)
+			if info.Reused {
+				reused.Add(1)
+			}
+		},
+	})
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				job, ok := queue.next()
+				if !ok {
+					return
+				}
+
+				info, err := c.fetch(traceCtx, job.url)
+				duplicate := false
+				if err == nil {
+					if duplicate, err = c.save(index, info); err != nil {
+						err = fmt.Errorf("failed to store result: %w", err)
+					}
+				}
+				if err == nil && job.depth < c.maxDepth {
+					c.enqueueLinks(queue, info, job.depth+1)
+				}
+
+				mu.Lock()
+				if err != nil {
+					result.Failures = append(result.Failures, CrawlFailure{
+						URL:     job.url,
+						Err:     err,
+						Timeout: errors.Is(err, ErrFetchTimeout),
+					})
+				} else if duplicate {
+					result.Duplicates++
+				} else {
+					result.Pages++
+				}
+				completed := result.Pages + result.Duplicates + len(result.Failures)
+				progress := CrawlProgress{Completed: completed, Failed: len(result.Failures)}
+				mu.Unlock()
+
+				c.tracker.complete(time.Now())
+				queue.done()
+				if c.onProgress != nil {
+					progress.Pending, progress.ETA = c.tracker.remaining(time.Now())
+					c.onProgress(progress)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	result.Requests = int(requests.Load())
+	result.ReusedConns = int(reused.Load())
+	if c.minReuse > 0 && result.Requests >= minRequestsForReuseWarning && result.ConnReuseRatio() < c.minReuse {
+		log.Printf("Warning: only %d of %d requests reused a connection (%.0f%%); check keep-alive and idle connection settings",
+			result.ReusedConns, result.Requests, result.ConnReuseRatio()*100)
+	}
+
+	return result, ctx.Err()
+}
+
+// authenticate runs the login step, making sure its cookies have a jar to live in
+func (c *Crawler) authenticate(ctx context.Context) error {
+	if c.client.client.Jar == nil {
+		jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+		if err != nil {
+			return fmt.Errorf("failed to create cookie jar: %w", err)
+		}
+		c.client.client.Jar = jar
+	}
+	if err := c.login(ctx, c.client); err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+	return nil
+}
+
+// enqueueLinks queues the page's links, and same-host iframes if enabled, that pass the URL filter
+func (c *Crawler) enqueueLinks(queue *frontier, info *PageInfo, depth int) {
+	targets := info.Links
+	if c.iframes {
+		targets = append(slices.Clip(targets), sameHostURLs(info.URL, info.Iframes)...)
+	}
+	for _, link := range targets {
+		if c.urlFilter != nil && !c.urlFilter(link) {
+			continue
+		}
+		if queue.push(crawlJob{url: link, depth: depth}) {
+			c.tracker.add(1)
+		}
+	}
+}
+
+// sameHostURLs returns the http(s) URLs in urls that share pageURL's host
+func sameHostURLs(pageURL string, urls []string) []string {
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	var same []string
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err == nil && (u.Scheme == "http" || u.Scheme == "https") && strings.EqualFold(u.Host, page.Host) {
+			same = append(same, raw)
+		}
+	}
+	return same
+}
+
+// fetch fetches a single page, applying the shared pool and per-fetch watchdog if configured
+func (c *Crawler) fetch(ctx context.Context, url string) (*PageInfo, error) {
+	if c.pool != nil {
+		if err := c.pool.Acquire(ctx); err != nil {
+			return nil, fmt.Errorf("worker pool error: %w", err)
+		}
+		defer c.pool.Release()
+	}
+
+	if c.fetchTimeout <= 0 {
+		return fetchPage(ctx, c.client, c.crypto, url, c.fetchOpts)
+	}
+
+	fetchCtx, cancel := context.WithTimeoutCause(ctx, c.fetchTimeout, ErrFetchTimeout)
+	defer cancel()
+
+	info, err := fetchPage(fetchCtx, c.client, c.crypto, url, c.fetchOpts)
+	// Only the watchdog firing counts as a timeout; cancellation of the crawl itself does not
+	if err != nil && ctx.Err() == nil && errors.Is(context.Cause(fetchCtx), ErrFetchTimeout) {
+		return nil, fmt.Errorf("%w after %v: %v", ErrFetchTimeout, c.fetchTimeout, err)
+	}
+	return info, err
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// stallingServer sends response headers and part of a body, then blocks until the
+// request is cancelled or the test ends
+func stallingServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><title>stalled"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+	return srv
+}
+
+func newTestCrawler(t *testing.T, opts ...CrawlerOption) *Crawler {
+	t.Helper()
+	crypto, err := NewCryptoUtils()
+	if err != nil {
+		t.Fatalf("NewCryptoUtils: %v", err)
+	}
+	return NewCrawler(NewHTTPClient(rate.Inf, 1), crypto, 2, opts...)
+}
+
+func TestCrawlTimeoutAbortsStalledReads(t *testing.T) {
+	srv := stallingServer(t)
+	crawler := newTestCrawler(t, WithCrawlTimeout(200*time.Millisecond))
+
+	start := time.Now()
+	result, err := crawler.Crawl(context.Background(), []string{srv.URL + "/a", srv.URL + "/b"})
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	// The client's own timeout is 30s; the crawl must stop long before it
+	if elapsed > 5*time.Second {
+		t.Errorf("Crawl returned after %v, want it to stop shortly after the 200ms crawl timeout", elapsed)
+	}
+	if result.Pages != 0 {
+		t.Errorf("Pages = %d, want 0", result.Pages)
+	}
+}
+
+func TestCrawlCancelAbortsStalledReads(t *testing.T) {
+	srv := stallingServer(t)
+	crawler := newTestCrawler(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := crawler.Crawl(ctx, []string{srv.URL})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Crawl returned after %v, want it to stop shortly after cancellation", elapsed)
+	}
+}
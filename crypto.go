@@ -0,0 +1,188 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// ErrInvalidSalt is returned when a stored salt is truncated or corrupted
+var ErrInvalidSalt = errors.New("invalid salt")
+
+// ValidateSalt checks that a salt loaded from storage is plausible:
+// it must be at least minSaltLength bytes long and not consist only of zero bytes
+func ValidateSalt(salt []byte) error {
+	if len(salt) < minSaltLength {
+		return fmt.Errorf("%w: got %d bytes, want at least %d", ErrInvalidSalt, len(salt), minSaltLength)
+	}
+	for _, b := range salt {
+		if b != 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: salt is all zero bytes", ErrInvalidSalt)
+}
+
+// decodeSalt decodes and validates the recorded salt. The salt must also match the
+// recorded SaltLen, which catches salts truncated in storage
+func (r HashResult) decodeSalt() ([]byte, error) {
+	salt, err := hex.DecodeString(r.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSalt, err)
+	}
+	if err := ValidateSalt(salt); err != nil {
+		return nil, err
+	}
+	if len(salt) != r.SaltLen {
+		return nil, fmt.Errorf("%w: got %d bytes, want %d", ErrInvalidSalt, len(salt), r.SaltLen)
+	}
+	return salt, nil
+}
+
+// Verify recomputes the digest over data with the parameters recorded in r and reports
+// whether it matches. Invalid parameters, including a damaged salt, are reported as an error
+func (r HashResult) Verify(data []byte) (bool, error) {
+	actual, err := r.compute(data)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare([]byte(actual), []byte(r.Digest)) == 1, nil
+}
+
+// VerifyTitle checks a title against the PBKDF2 hash previously produced by HashTitle,
+// using the salt, iteration count and key length stored with it. The stored salt is
+// validated first, so a damaged salt is reported as an error instead of silently
+// failing the comparison
+func (c *CryptoUtils) VerifyTitle(title string, stored map[string]HashResult) (bool, error) {
+	result, ok := stored["pbkdf2-sha3"]
+	if !ok {
+		return false, errors.New("no pbkdf2-sha3 hash stored")
+	}
+	return result.Verify([]byte(title))
+}
+
+// ShortHash returns the first hexLen hex characters of the full BLAKE2b-256 digest of data,
+// for compact IDs in displays and URLs. hexLen must be between 1 and 64; ShortHash panics
+// otherwise, as requesting more than the digest holds is a programming error.
+//
+// Truncating keeps 4 bits per character, so among n distinct inputs a collision becomes
+// likely (about 50%) once n reaches roughly 2^(2*hexLen): about 65 thousand IDs at 8
+// characters and 4 billion at 16. Short IDs are fine for display but should not be used
+// where collisions are a security concern
+func ShortHash(data []byte, hexLen int) string {
+	if hexLen < 1 || hexLen > 2*blake2b.Size256 {
+		panic(fmt.Sprintf("ShortHash: hexLen %d out of range [1, %d]", hexLen, 2*blake2b.Size256))
+	}
+	return hashContent(data)[:hexLen]
+}
+
+// TitleHashBatch holds the hashes of a batch of titles with each distinct title stored once
+type TitleHashBatch struct {
+	// Hashes maps each distinct title to its HashTitle result
+	Hashes map[string]map[string]HashResult
+	// Index holds the title at each input position, a key into Hashes
+	Index []string
+}
+
+// HashTitles hashes a batch of titles, computing and storing each distinct title only once
+func (c *CryptoUtils) HashTitles(titles []string) (*TitleHashBatch, error) {
+	batch := &TitleHashBatch{
+		Hashes: make(map[string]map[string]HashResult),
+		Index:  make([]string, len(titles)),
+	}
+	for i, title := range titles {
+		batch.Index[i] = title
+		if _, ok := batch.Hashes[title]; ok {
+			continue
+		}
+		hashes, err := c.HashTitle(title)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash title %d: %w", i, err)
+		}
+		batch.Hashes[title] = hashes
+	}
+	return batch, nil
+}
+
+// At returns the hashes for the input at position i. Inputs with the same title share the map
+func (b *TitleHashBatch) At(i int) map[string]HashResult {
+	return b.Hashes[b.Index[i]]
+}
+
+// HashAgreement measures how often two HashTitle algorithms make the same dedup decision.
+// Every pair of titles is classified as same or different by each algorithm, and the
+// result is the fraction of pairs both algorithms classify identically. Rather than
+// comparing all n(n-1)/2 pairs, the pair counts are derived from digest group sizes,
+// so the cost is one digest per title per algorithm plus O(n) counting; note that
+// pbkdf2-sha3 is deliberately slow, which dominates the cost on large corpora.
+// It returns 1 for fewer than two titles and 0 if either algorithm is unknown
+func (c *CryptoUtils) HashAgreement(titles []string, algoA, algoB string) float64 {
+	pairs := func(n int) int { return n * (n - 1) / 2 }
+
+	groupsA := make(map[string]int)
+	groupsB := make(map[string]int)
+	groupsAB := make(map[[2]string]int)
+	for _, title := range titles {
+		a, err := c.hash(algoA, []byte(title))
+		if err != nil {
+			return 0
+		}
+		b, err := c.hash(algoB, []byte(title))
+		if err != nil {
+			return 0
+		}
+		groupsA[a.Digest]++
+		groupsB[b.Digest]++
+		groupsAB[[2]string{a.Digest, b.Digest}]++
+	}
+
+	total := pairs(len(titles))
+	if total == 0 {
+		return 1
+	}
+
+	var sameA, sameB, sameBoth int
+	for _, n := range groupsA {
+		sameA += pairs(n)
+	}
+	for _, n := range groupsB {
+		sameB += pairs(n)
+	}
+	for _, n := range groupsAB {
+		sameBoth += pairs(n)
+	}
+
+	// Pairs both call the same, plus pairs neither calls the same
+	differentBoth := total - sameA - sameB + sameBoth
+	return float64(sameBoth+differentBoth) / float64(total)
+}
+
+// ValidateAllIntegrity verifies every result in expected against content, using the
+// parameters recorded in each result, and succeeds only if all of them match. It returns
+// the sorted names of the algorithms that failed; unknown algorithms and invalid parameters
+// count as failures. A single mismatch, which may indicate partial corruption or tampering,
+// fails the check
+func (c *CryptoUtils) ValidateAllIntegrity(content string, expected map[string]HashResult) (bool, []string) {
+	algos := make([]string, 0, len(expected))
+	for algo := range expected {
+		algos = append(algos, algo)
+	}
+	sort.Strings(algos)
+
+	var failed []string
+	for _, algo := range algos {
+		result := expected[algo]
+		if result.Algorithm != algo {
+			failed = append(failed, algo)
+			continue
+		}
+		if ok, err := result.Verify([]byte(content)); err != nil || !ok {
+			failed = append(failed, algo)
+		}
+	}
+	return len(algos) > 0 && len(failed) == 0, failed
+}
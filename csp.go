@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// parseCSP parses Content-Security-Policy values into directive -> sources.
+// Within a policy the first occurrence of a directive wins, as browsers do; when several
+// policies are given, their sources are merged per directive, which approximates the
+// combined posture rather than the exact intersection browsers enforce
+func parseCSP(policies []string) map[string][]string {
+	csp := make(map[string][]string)
+	for _, policy := range policies {
+		seen := make(map[string]bool)
+		for _, directive := range strings.Split(policy, ";") {
+			fields := strings.Fields(directive)
+			if len(fields) == 0 {
+				continue
+			}
+			name := strings.ToLower(fields[0])
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			sources := csp[name]
+			for _, src := range fields[1:] {
+				if !containsString(sources, src) {
+					sources = append(sources, src)
+				}
+			}
+			if sources == nil {
+				sources = []string{}
+			}
+			csp[name] = sources
+		}
+	}
+	return csp
+}
+
+// extractMetaCSP returns the policies declared with <meta http-equiv="Content-Security-Policy">
+func extractMetaCSP(n *html.Node) []string {
+	var policies []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "meta" &&
+			strings.EqualFold(strings.TrimSpace(getAttr(n, "http-equiv")), "Content-Security-Policy") {
+			policies = append(policies, getAttr(n, "content"))
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return policies
+}
+
+// cspWeaknesses flags common weaknesses in a parsed policy
+func cspWeaknesses(csp map[string][]string) []string {
+	if len(csp) == 0 {
+		return []string{"no Content-Security-Policy"}
+	}
+
+	// script-src falls back to default-src when absent
+	directive := "script-src"
+	scripts, ok := csp[directive]
+	if !ok {
+		directive = "default-src"
+		scripts, ok = csp[directive]
+	}
+	if !ok {
+		return []string{"CSP does not restrict scripts (no script-src or default-src)"}
+	}
+
+	var weaknesses []string
+	for _, src := range scripts {
+		switch strings.ToLower(src) {
+		case "'unsafe-inline'":
+			weaknesses = append(weaknesses, "CSP "+directive+" allows 'unsafe-inline'")
+		case "'unsafe-eval'":
+			weaknesses = append(weaknesses, "CSP "+directive+" allows 'unsafe-eval'")
+		case "*", "http:", "https:", "data:":
+			weaknesses = append(weaknesses, "CSP "+directive+" allows any source via "+src)
+		}
+	}
+	return weaknesses
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// AliasStore is a ResultStore that is also told when a page turns out to be a duplicate.
+// Stores that persist pages as soon as they are put can implement it to record aliases
+// found later in the crawl; stores that keep the *PageInfo, like MemoryStore, see them
+// in PageInfo.Aliases without it
+type AliasStore interface {
+	ResultStore
+	PutAlias(canonicalURL, alias string) error
+}
+
+// WithContentDedup collapses pages with byte-identical bodies, as identified by their
+// BLAKE2b ContentHash, into the first one crawled. Later URLs serving the same content
+// are added to that page's Aliases instead of being stored, and counted in
+// CrawlResult.Duplicates. Pages without a ContentHash, as in metadata-only mode, are
+// never collapsed
+func WithContentDedup() CrawlerOption {
+	return func(c *Crawler) {
+		c.dedup = true
+	}
+}
+
+// contentIndex maps content hashes to the canonical page crawled for them
+type contentIndex struct {
+	mu    sync.Mutex
+	pages map[string]*contentEntry
+}
+
+// contentEntry is the canonical page for a content hash. stored is closed once the page
+// has been put in the store, and Aliases of the page are only changed after that, under
+// the index lock
+type contentEntry struct {
+	page   *PageInfo
+	stored chan struct{}
+}
+
+// newContentIndex creates an empty index
+func newContentIndex() *contentIndex {
+	return &contentIndex{pages: make(map[string]*contentEntry)}
+}
+
+// save writes a crawled page to the store and reports whether it was collapsed into an
+// earlier page with the same content instead. The index lock only guards claiming a hash
+// and recording aliases, so slow stores do not serialize the crawl; a duplicate of a page
+// still being stored waits for it, and takes its place if storing it fails
+func (c *Crawler) save(index *contentIndex, info *PageInfo) (bool, error) {
+	if index == nil || info.ContentHash == "" {
+		return false, c.store.Put(info)
+	}
+
+	for {
+		index.mu.Lock()
+		entry, ok := index.pages[info.ContentHash]
+		if !ok {
+			entry = &contentEntry{page: info, stored: make(chan struct{})}
+			index.pages[info.ContentHash] = entry
+			index.mu.Unlock()
+
+			err := c.store.Put(info)
+			if err != nil {
+				// Let a later page with the same content become canonical instead
+				index.mu.Lock()
+				delete(index.pages, info.ContentHash)
+				index.mu.Unlock()
+			}
+			close(entry.stored)
+			return false, err
+		}
+		index.mu.Unlock()
+
+		<-entry.stored
+		index.mu.Lock()
+		if index.pages[info.ContentHash] != entry {
+			// Storing the canonical page failed
+			index.mu.Unlock()
+			continue
+		}
+		entry.page.Aliases = append(entry.page.Aliases, info.URL)
+		index.mu.Unlock()
+
+		if store, ok := c.store.(AliasStore); ok {
+			if err := store.PutAlias(entry.page.URL, info.URL); err != nil {
+				return true, fmt.Errorf("failed to store alias: %w", err)
+			}
+		}
+		return true, nil
+	}
+}
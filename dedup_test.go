@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowStore is a MemoryStore whose Put takes delay, like a remote database. It records
+// the highest number of Put calls in progress at once
+type slowStore struct {
+	*MemoryStore
+	delay time.Duration
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	aliases     map[string][]string
+}
+
+func (s *slowStore) Put(info *PageInfo) error {
+	s.mu.Lock()
+	s.inFlight++
+	s.maxInFlight = max(s.maxInFlight, s.inFlight)
+	s.mu.Unlock()
+
+	time.Sleep(s.delay)
+
+	s.mu.Lock()
+	s.inFlight--
+	s.mu.Unlock()
+	return s.MemoryStore.Put(info)
+}
+
+func (s *slowStore) PutAlias(canonicalURL, alias string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.aliases[canonicalURL] = append(s.aliases[canonicalURL], alias)
+	return nil
+}
+
+func TestContentDedupStoresConcurrently(t *testing.T) {
+	// /page/N and /copy/N serve the same body
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><head><title>%s</title></head><body>same</body></html>", r.URL.Path[len(r.URL.Path)-1:])
+	}))
+	t.Cleanup(srv.Close)
+
+	const pages = 4
+	var urls []string
+	for i := range pages {
+		urls = append(urls, fmt.Sprintf("%s/page/%d", srv.URL, i))
+	}
+	for i := range pages {
+		urls = append(urls, fmt.Sprintf("%s/copy/%d", srv.URL, i))
+	}
+
+	store := &slowStore{MemoryStore: NewMemoryStore(), delay: 300 * time.Millisecond, aliases: make(map[string][]string)}
+	crawler := newTestCrawler(t, WithContentDedup(), WithResultStore(store))
+	crawler.workers = len(urls)
+
+	result, err := crawler.Crawl(context.Background(), urls)
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	if store.maxInFlight < 2 {
+		t.Errorf("at most %d Put calls ran at once, want pages with different content stored concurrently", store.maxInFlight)
+	}
+
+	if result.Pages != pages || result.Duplicates != pages {
+		t.Fatalf("Pages, Duplicates = %d, %d, want %d, %d", result.Pages, result.Duplicates, pages, pages)
+	}
+	for _, info := range store.Pages() {
+		if len(info.Aliases) != 1 {
+			t.Errorf("%s: Aliases = %v, want one copy", info.URL, info.Aliases)
+			continue
+		}
+		if !slices.Equal(store.aliases[info.URL], info.Aliases) {
+			t.Errorf("%s: PutAlias got %v, want %v", info.URL, store.aliases[info.URL], info.Aliases)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// WithDigestAuth answers HTTP Digest challenges (RFC 7616) with the given credentials.
+// A 401 response carrying a Digest challenge is retried once with an Authorization header.
+// The server's nonce is then reused for later requests to the same host, with the nonce
+// count incremented each time, until the server rejects it or marks it stale.
+// MD5, MD5-sess, SHA-256 and SHA-256-sess are supported with qop "auth" or no qop
+func WithDigestAuth(username, password string) ClientOption {
+	return func(c *HTTPClient) {
+		c.digestAuth = &digestAuth{
+			username:   username,
+			password:   password,
+			challenges: make(map[string]*digestChallenge),
+		}
+	}
+}
+
+// digestChallenge is a parsed WWW-Authenticate: Digest challenge and its nonce count
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+	nc        uint32
+}
+
+// digestAuth holds the credentials and the last challenge seen from each host
+type digestAuth struct {
+	username string
+	password string
+
+	mu         sync.Mutex
+	challenges map[string]*digestChallenge
+}
+
+// doWithDigestAuth sends req, answering a Digest challenge if the server responds with one
+func (c *HTTPClient) doWithDigestAuth(req *http.Request) (*http.Response, error) {
+	if c.digestAuth == nil {
+		return c.doWithRetries(req)
+	}
+
+	// Reuse a known nonce so most requests need no extra round trip
+	if header, ok := c.digestAuth.authorization(req); ok {
+		req.Header.Set("Authorization", header)
+	}
+	resp, err := c.doWithRetries(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge, ok := parseDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+	if !ok {
+		return resp, nil
+	}
+
+	if !replayable(req) {
+		return resp, nil
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	c.digestAuth.store(req.URL.Host, challenge)
+	retry, err := rewindRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	header, _ := c.digestAuth.authorization(retry)
+	retry.Header.Set("Authorization", header)
+	return c.doWithRetries(retry)
+}
+
+// store remembers the latest challenge for host, resetting the nonce count
+func (d *digestAuth) store(host string, challenge *digestChallenge) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.challenges[host] = challenge
+}
+
+// authorization computes the Authorization header for req from the host's stored challenge
+func (d *digestAuth) authorization(req *http.Request) (string, bool) {
+	d.mu.Lock()
+	ch, ok := d.challenges[req.URL.Host]
+	if !ok {
+		d.mu.Unlock()
+		return "", false
+	}
+	ch.nc++
+	nc := fmt.Sprintf("%08x", ch.nc)
+	challenge := *ch
+	d.mu.Unlock()
+
+	newHash := md5.New
+	if strings.HasPrefix(strings.ToUpper(challenge.algorithm), "SHA-256") {
+		newHash = sha256.New
+	}
+	h := func(parts ...string) string {
+		sum := newHash()
+		io.WriteString(sum, strings.Join(parts, ":"))
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	cnonce := make([]byte, 16)
+	rand.Read(cnonce)
+	cnonceHex := hex.EncodeToString(cnonce)
+
+	uri := req.URL.RequestURI()
+	ha1 := h(d.username, challenge.realm, d.password)
+	if strings.HasSuffix(strings.ToLower(challenge.algorithm), "-sess") {
+		ha1 = h(ha1, challenge.nonce, cnonceHex)
+	}
+	ha2 := h(req.Method, uri)
+
+	var response string
+	if challenge.qop == "" {
+		// RFC 2069 compatibility: no qop, nonce count or client nonce
+		response = h(ha1, challenge.nonce, ha2)
+	} else {
+		response = h(ha1, challenge.nonce, nc, cnonceHex, challenge.qop, ha2)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `Digest username=%q, realm=%q, nonce=%q, uri=%q, response=%q`,
+		d.username, challenge.realm, challenge.nonce, uri, response)
+	if challenge.algorithm != "" {
+		fmt.Fprintf(&sb, ", algorithm=%s", challenge.algorithm)
+	}
+	if challenge.qop != "" {
+		fmt.Fprintf(&sb, `, qop=%s, nc=%s, cnonce=%q`, challenge.qop, nc, cnonceHex)
+	}
+	if challenge.opaque != "" {
+		fmt.Fprintf(&sb, ", opaque=%q", challenge.opaque)
+	}
+	return sb.String(), true
+}
+
+// parseDigestChallenge returns the first Digest challenge with a supported algorithm and qop
+func parseDigestChallenge(values []string) (*digestChallenge, bool) {
+	for _, value := range values {
+		scheme, rest, _ := strings.Cut(strings.TrimSpace(value), " ")
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+		params := parseAuthParams(rest)
+		challenge := &digestChallenge{
+			realm:     params["realm"],
+			nonce:     params["nonce"],
+			opaque:    params["opaque"],
+			algorithm: params["algorithm"],
+		}
+		if challenge.nonce == "" || !supportedDigestAlgorithm(challenge.algorithm) {
+			continue
+		}
+		if qops, ok := params["qop"]; ok {
+			for _, qop := range strings.Split(qops, ",") {
+				if strings.TrimSpace(qop) == "auth" {
+					challenge.qop = "auth"
+				}
+			}
+			// Only auth-int was offered, which needs the entity body hash
+			if challenge.qop == "" {
+				continue
+			}
+		}
+		return challenge, true
+	}
+	return nil, false
+}
+
+// supportedDigestAlgorithm reports whether the challenge's algorithm can be computed
+func supportedDigestAlgorithm(algorithm string) bool {
+	switch strings.ToUpper(algorithm) {
+	case "", "MD5", "MD5-SESS", "SHA-256", "SHA-256-SESS":
+		return true
+	}
+	return false
+}
+
+// parseAuthParams parses comma-separated key=value auth parameters, where values may be
+// quoted strings containing commas and backslash escapes. Keys are lowercased
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return params
+		}
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			return params
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		rest = strings.TrimLeft(rest, " \t")
+
+		var value strings.Builder
+		if strings.HasPrefix(rest, `"`) {
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				value.WriteByte(rest[i])
+			}
+			s = rest[min(i+1, len(rest)):]
+		} else {
+			token, tail, _ := strings.Cut(rest, ",")
+			value.WriteString(strings.TrimSpace(token))
+			s = tail
+		}
+		params[key] = value.String()
+	}
+}
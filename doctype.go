@@ -0,0 +1,132 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// html5Doctype is the rendered form of the standard HTML5 doctype
+const html5Doctype = "<!DOCTYPE html>"
+
+// quirksPublicPrefixes are the public identifiers that put browsers in quirks mode,
+// as listed in the HTML specification
+var quirksPublicPrefixes = []string{
+	"+//silmaril//dtd html pro v0r11 19970101//",
+	"-//as//dtd html 3.0 aswedit + extensions//",
+	"-//advasoft ltd//dtd html 3.0 aswedit + extensions//",
+	"-//ietf//dtd html 2.0 level 1//",
+	"-//ietf//dtd html 2.0 level 2//",
+	"-//ietf//dtd html 2.0 strict level 1//",
+	"-//ietf//dtd html 2.0 strict level 2//",
+	"-//ietf//dtd html 2.0 strict//",
+	"-//ietf//dtd html 2.0//",
+	"-//ietf//dtd html 2.1e//",
+	"-//ietf//dtd html 3.0//",
+	"-//ietf//dtd html 3.2 final//",
+	"-//ietf//dtd html 3.2//",
+	"-//ietf//dtd html 3//",
+	"-//ietf//dtd html level 0//",
+	"-//ietf//dtd html level 1//",
+	"-//ietf//dtd html level 2//",
+	"-//ietf//dtd html level 3//",
+	"-//ietf//dtd html strict level 0//",
+	"-//ietf//dtd html strict level 1//",
+	"-//ietf//dtd html strict level 2//",
+	"-//ietf//dtd html strict level 3//",
+	"-//ietf//dtd html strict//",
+	"-//ietf//dtd html//",
+	"-//metrius//dtd metrius presentational//",
+	"-//microsoft//dtd internet explorer 2.0 html strict//",
+	"-//microsoft//dtd internet explorer 2.0 html//",
+	"-//microsoft//dtd internet explorer 2.0 tables//",
+	"-//microsoft//dtd internet explorer 3.0 html strict//",
+	"-//microsoft//dtd internet explorer 3.0 html//",
+	"-//microsoft//dtd internet explorer 3.0 tables//",
+	"-//netscape comm. corp.//dtd html//",
+	"-//netscape comm. corp.//dtd strict html//",
+	"-//o'reilly and associates//dtd html 2.0//",
+	"-//o'reilly and associates//dtd html extended 1.0//",
+	"-//o'reilly and associates//dtd html extended relaxed 1.0//",
+	"-//sq//dtd html 2.0 hotmetal + extensions//",
+	"-//softquad software//dtd hotmetal pro 6.0::19990601::extensions to html 4.0//",
+	"-//softquad//dtd hotmetal pro 4.0::19971010::extensions to html 4.0//",
+	"-//spyglass//dtd html 2.0 extended//",
+	"-//sun microsystems corp.//dtd hotjava html//",
+	"-//sun microsystems corp.//dtd hotjava strict html//",
+	"-//w3c//dtd html 3 1995-03-24//",
+	"-//w3c//dtd html 3.2 draft//",
+	"-//w3c//dtd html 3.2 final//",
+	"-//w3c//dtd html 3.2//",
+	"-//w3c//dtd html 3.2s draft//",
+	"-//w3c//dtd html 4.0 frameset//",
+	"-//w3c//dtd html 4.0 transitional//",
+	"-//w3c//dtd html experimental 19960712//",
+	"-//w3c//dtd html experimental 970421//",
+	"-//w3c//dtd w3 html//",
+	"-//w3o//dtd w3 html 3.0//",
+	"-//webtechs//dtd mozilla html 2.0//",
+	"-//webtechs//dtd mozilla html//",
+}
+
+// quirksWithoutSystemPrefixes trigger quirks mode only when no system identifier is given
+var quirksWithoutSystemPrefixes = []string{
+	"-//w3c//dtd html 4.01 frameset//",
+	"-//w3c//dtd html 4.01 transitional//",
+}
+
+// extractDoctype returns the document's rendered doctype, or "" if it has none,
+// and whether the doctype puts browsers in quirks mode
+func extractDoctype(doc *html.Node) (doctype string, quirks bool) {
+	for n := doc.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type != html.DoctypeNode {
+			continue
+		}
+		var sb strings.Builder
+		if err := html.Render(&sb, n); err != nil {
+			return "", true
+		}
+		return sb.String(), isQuirksDoctype(n)
+	}
+	// A missing doctype always means quirks mode
+	return "", true
+}
+
+// isQuirksDoctype applies the HTML specification's doctype rules for quirks mode
+func isQuirksDoctype(n *html.Node) bool {
+	if !strings.EqualFold(n.Data, "html") {
+		return true
+	}
+
+	var public, system string
+	var hasSystem bool
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "public":
+			public = strings.ToLower(attr.Val)
+		case "system":
+			system, hasSystem = strings.ToLower(attr.Val), true
+		}
+	}
+
+	switch {
+	case public == "-//w3o//dtd w3 html strict 3.0//en//",
+		public == "-/w3c/dtd html 4.0 transitional/en",
+		public == "html",
+		system == "http://www.ibm.com/data/dtd/v11/ibmxhtml1-transitional.dtd":
+		return true
+	}
+	for _, prefix := range quirksPublicPrefixes {
+		if strings.HasPrefix(public, prefix) {
+			return true
+		}
+	}
+	if !hasSystem {
+		for _, prefix := range quirksWithoutSystemPrefixes {
+			if strings.HasPrefix(public, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dohTimeout bounds a single DNS-over-HTTPS query
+const dohTimeout = 10 * time.Second
+
+// WithDoH resolves hostnames through an RFC 8484 DNS-over-HTTPS endpoint such as
+// "https://cloudflare-dns.com/dns-query" instead of the local resolver. The transport
+// dials the resolved IPs directly, while TLS still verifies and sends SNI for the
+// original hostname. Queries share the client's rate limiter and answers are cached
+// for their TTL. The endpoint's own hostname is resolved with the system resolver
+func WithDoH(endpoint string) ClientOption {
+	return func(c *HTTPClient) {
+		c.doh = &dohResolver{
+			endpoint: endpoint,
+			cache:    make(map[string]dohEntry),
+		}
+	}
+}
+
+// dohEntry is a cached resolution
+type dohEntry struct {
+	addrs   []netip.Addr
+	expires time.Time
+}
+
+// dohResolver resolves hostnames over DNS-over-HTTPS with a TTL cache
+type dohResolver struct {
+	endpoint string
+	client   *HTTPClient
+	dialer   net.Dialer
+
+	mu    sync.Mutex
+	cache map[string]dohEntry
+}
+
+// dialContext is an http.Transport DialContext that resolves the host via DoH
+func (r *dohResolver) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := r.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	// Try each address in turn until one connects
+	var dialErr error
+	for _, ip := range addrs {
+		conn, err := r.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		dialErr = err
+	}
+	return nil, dialErr
+}
+
+// resolve returns the addresses of host, from the cache when still fresh
+func (r *dohResolver) resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{ip}, nil
+	}
+
+	r.mu.Lock()
+	entry, ok := r.cache[host]
+	r.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	var (
+		addrs    []netip.Addr
+		minTTL   uint32
+		queryErr error
+	)
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		found, ttl, err := r.query(ctx, host, qtype)
+		if err != nil {
+			queryErr = err
+			continue
+		}
+		addrs = append(addrs, found...)
+		if len(found) > 0 && (minTTL == 0 || ttl < minTTL) {
+			minTTL = ttl
+		}
+	}
+	if len(addrs) == 0 {
+		if queryErr == nil {
+			queryErr = errors.New("no addresses found")
+		}
+		return nil, fmt.Errorf("DoH lookup of %s failed: %w", host, queryErr)
+	}
+
+	if minTTL > 0 {
+		r.mu.Lock()
+		r.cache[host] = dohEntry{addrs: addrs, expires: time.Now().Add(time.Duration(minTTL) * time.Second)}
+		r.mu.Unlock()
+	}
+	return addrs, nil
+}
+
+// query sends a single DoH question and returns the addresses and smallest TTL in the answer
+func (r *dohResolver) query(ctx context.Context, host string, qtype dnsmessage.Type) ([]netip.Addr, uint32, error) {
+	name, err := dnsmessage.NewName(strings.TrimSuffix(host, ".") + ".")
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid hostname: %w", err)
+	}
+
+	// RFC 8484 recommends an ID of 0 so that responses are cache friendly
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{RecursionDesired: true})
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, 0, err
+	}
+	if err := b.Question(dnsmessage.Question{Name: name, Type: qtype, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, 0, err
+	}
+	msg, err := b.Finish()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// The dial context carries the outer request's values, including its redirect trace and
+	// client trace, so the query runs on a clean context that only shares its cancellation
+	queryCtx, cancel := context.WithTimeout(context.Background(), dohTimeout)
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+	req, err := http.NewRequestWithContext(queryCtx, "GET", r.endpoint+"?dns="+base64.RawURLEncoding.EncodeToString(msg), nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("DoH request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("unexpected DoH status: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read DoH response: %w", err)
+	}
+
+	return parseDNSAnswer(data)
+}
+
+// parseDNSAnswer extracts A/AAAA addresses and the smallest TTL from a DNS response
+func parseDNSAnswer(data []byte) ([]netip.Addr, uint32, error) {
+	var p dnsmessage.Parser
+	header, err := p.Start(data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse DNS response: %w", err)
+	}
+	if header.RCode != dnsmessage.RCodeSuccess {
+		return nil, 0, fmt.Errorf("DNS error: %v", header.RCode)
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse DNS response: %w", err)
+	}
+
+	var (
+		addrs  []netip.Addr
+		minTTL uint32
+	)
+	for {
+		h, err := p.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to parse DNS answer: %w", err)
+		}
+
+		switch h.Type {
+		case dnsmessage.TypeA:
+			res, err := p.AResource()
+			if err != nil {
+				return nil, 0, fmt.Errorf("failed to parse A record: %w", err)
+			}
+			addrs = append(addrs, netip.AddrFrom4(res.A))
+		case dnsmessage.TypeAAAA:
+			res, err := p.AAAAResource()
+			if err != nil {
+				return nil, 0, fmt.Errorf("failed to parse AAAA record: %w", err)
+			}
+			addrs = append(addrs, netip.AddrFrom16(res.AAAA))
+		default:
+			if err := p.SkipAnswer(); err != nil {
+				return nil, 0, fmt.Errorf("failed to parse DNS answer: %w", err)
+			}
+			continue
+		}
+		if minTTL == 0 || h.TTL < minTTL {
+			minTTL = h.TTL
+		}
+	}
+	return addrs, minTTL, nil
+}
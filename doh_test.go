@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/time/rate"
+)
+
+// loopbackDoHServer answers every A question with 127.0.0.1 and every other
+// question with an empty answer
+func loopbackDoHServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, err := base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var p dnsmessage.Parser
+		header, err := p.Start(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		question, err := p.Question()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: header.ID, Response: true})
+		b.StartQuestions()
+		b.Question(question)
+		b.StartAnswers()
+		if question.Type == dnsmessage.TypeA {
+			b.AResource(dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 60},
+				dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}})
+		}
+		msg, err := b.Finish()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(msg)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDoHKeepsRedirectTrace(t *testing.T) {
+	doh := loopbackDoHServer(t)
+
+	var port string
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, _, _ := net.SplitHostPort(r.Host); host == "a.test" {
+			http.Redirect(w, r, "http://b.test:"+port+"/", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><title>b</title></head></html>"))
+	}))
+	t.Cleanup(site.Close)
+	_, port, _ = net.SplitHostPort(site.Listener.Addr().String())
+
+	crypto, err := NewCryptoUtils()
+	if err != nil {
+		t.Fatalf("NewCryptoUtils: %v", err)
+	}
+	client := NewHTTPClient(rate.Inf, 1, WithDoH(doh.URL))
+
+	info, err := fetchPage(context.Background(), client, crypto, "http://a.test:"+port+"/", FetchOptions{})
+	if err != nil {
+		t.Fatalf("fetchPage: %v", err)
+	}
+	if info.Title != "b" {
+		t.Errorf("Title = %q, want %q", info.Title, "b")
+	}
+	if len(info.Redirects) != 1 || info.Redirects[0].StatusCode != http.StatusFound {
+		t.Errorf("Redirects = %+v, want the single 302 hop from a.test", info.Redirects)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// DownloadResult reports the outcome of downloading one URL
+type DownloadResult struct {
+	URL  string
+	Path string
+	Size int64
+	// Skipped is set when an identical file already existed at Path
+	Skipped bool
+	Err     error
+}
+
+// DownloadProgress is reported each time DownloadAll finishes a file
+type DownloadProgress struct {
+	Completed int
+	Total     int
+	Bytes     int64
+}
+
+// WithDownloadProgress calls fn after each file DownloadAll finishes, successful or not
+func WithDownloadProgress(fn func(DownloadProgress)) ClientOption {
+	return func(c *HTTPClient) {
+		c.downloadProgress = fn
+	}
+}
+
+// DownloadAll fetches every URL into destDir using up to concurrency parallel downloads.
+// Requests go through the client's rate limiter and retry policy. File names come from the
+// last URL path segment, made unique within the batch. A file whose content hash matches
+// an existing file at the same path is not rewritten and is reported as Skipped. The hash
+// can only be compared after downloading, so skipping saves bandwidth only when the client
+// already holds ETag or Last-Modified validators for that content, from an earlier
+// DownloadAll or HasChanged call: it then sends a conditional GET and a 304 skips the body.
+// Per-file failures are reported in the results; the error is only set if destDir cannot
+// be created or ctx is cancelled
+func (c *HTTPClient) DownloadAll(ctx context.Context, urls []string, destDir string, concurrency int) ([]DownloadResult, error) {
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create destination directory: %w", err)
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]DownloadResult, len(urls))
+	for i, name := range uniqueFileNames(urls) {
+		results[i] = DownloadResult{URL: urls[i], Path: filepath.Join(destDir, name)}
+	}
+	started := make([]bool, len(urls))
+	jobs := make(chan int)
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		progress DownloadProgress
+	)
+	progress.Total = len(urls)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				result := &results[idx]
+				result.Size, result.Skipped, result.Err = c.downloadFile(ctx, result.URL, result.Path)
+
+				mu.Lock()
+				progress.Completed++
+				progress.Bytes += result.Size
+				snapshot := progress
+				mu.Unlock()
+				if c.downloadProgress != nil {
+					c.downloadProgress(snapshot)
+				}
+			}
+		}()
+	}
+
+	// Feed downloads to the workers until done or cancelled
+feed:
+	for idx := range urls {
+		select {
+		case jobs <- idx:
+			started[idx] = true
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Downloads never handed to a worker fail with the cancellation error
+	if err := ctx.Err(); err != nil {
+		for i := range results {
+			if !started[i] {
+				results[i].Err = err
+			}
+		}
+		return results, err
+	}
+	return results, nil
+}
+
+// downloadFile downloads rawURL to dest via a temporary file, hashing it on the way,
+// and keeps the existing file if it already has the same content
+func (c *HTTPClient) downloadFile(ctx context.Context, rawURL, dest string) (size int64, skipped bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Ask for the body only if it differs from the existing file
+	existing, _ := hashFile(dest)
+	if v, ok := c.validators.get(rawURL); ok && existing != "" && v.contentHash == existing {
+		if v.etag != "" {
+			req.Header.Set("If-None-Match", v.etag)
+		}
+		if v.lastModified != "" {
+			req.Header.Set("If-Modified-Since", v.lastModified)
+		}
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return 0, true, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, false, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".download-*")
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash, _ := blake2b.New256(nil)
+	size, err = io.Copy(io.MultiWriter(tmp, hash), resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return size, false, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	c.validators.put(rawURL, cachedValidators{
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		contentHash:  sum,
+	})
+	if existing == sum {
+		return size, true, nil
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return size, false, fmt.Errorf("failed to set file mode: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return size, false, fmt.Errorf("failed to move file into place: %w", err)
+	}
+	return size, false, nil
+}
+
+// hashFile computes the BLAKE2b-256 content hash of a file
+func hashFile(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash, _ := blake2b.New256(nil)
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// uniqueFileNames derives a file name for each URL from its last path segment,
+// adding a short hash of the URL when two URLs would share a name
+func uniqueFileNames(urls []string) []string {
+	names := make([]string, len(urls))
+	used := make(map[string]bool)
+	for i, rawURL := range urls {
+		name := "index"
+		if u, err := url.Parse(rawURL); err == nil {
+			if base := path.Base(u.Path); base != "." && base != "/" {
+				name = base
+			}
+		}
+		name = strings.Map(func(r rune) rune {
+			if r == '/' || r == '\\' || r == 0 {
+				return '_'
+			}
+			return r
+		}, name)
+		// Names that refer to a directory would place the file outside destDir
+		if name == "." || name == ".." {
+			name = "index"
+		}
+
+		if used[name] {
+			ext := path.Ext(name)
+			name = strings.TrimSuffix(name, ext) + "-" + hashContent([]byte(rawURL))[:8] + ext
+		}
+		used[name] = true
+		names[i] = name
+	}
+	return names
+}
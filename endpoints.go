@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// maxEndpointBytes caps the size of a data endpoint response that will be decoded
+const maxEndpointBytes = 4 << 20
+
+// endpointKeySuffixes mark data-* attributes whose relative values are still treated as URLs
+var endpointKeySuffixes = []string{"-url", "-uri", "-src", "-href", "-endpoint", "-api"}
+
+// extractDataEndpoints returns the absolute http(s) URLs found in data-* attributes,
+// deduplicated in document order. If attrs is non-empty only those attribute names are
+// inspected. This is a heuristic: values are taken as URLs when they are absolute or
+// root-relative, or when the attribute name ends in a URL-like suffix such as -url or
+// -endpoint. It does not execute JavaScript, so endpoints built at runtime are missed
+func extractDataEndpoints(n *html.Node, base *url.URL, attrs []string) []string {
+	endpoints := newOrderedSet()
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for _, attr := range n.Attr {
+				if !strings.HasPrefix(attr.Key, "data-") {
+					continue
+				}
+				if len(attrs) > 0 && !containsString(attrs, attr.Key) {
+					continue
+				}
+				if !looksLikeEndpoint(attr.Key, attr.Val) {
+					continue
+				}
+				if endpoint := resolveLink(base, attr.Val); endpoint != "" {
+					endpoints.add(endpoint)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return endpoints.list()
+}
+
+// looksLikeEndpoint reports whether a data-* attribute value is plausibly a URL
+func looksLikeEndpoint(key, value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.ContainsAny(value, " \t\n{}<>") {
+		return false
+	}
+	lower := strings.ToLower(value)
+	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "/") {
+		return true
+	}
+	for _, suffix := range endpointKeySuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchDataEndpoints fetches each endpoint as JSON, logging failures, and returns the
+// decoded documents keyed by URL
+func fetchDataEndpoints(ctx context.Context, httpClient *HTTPClient, endpoints []string) map[string]any {
+	data := make(map[string]any)
+	for _, endpoint := range endpoints {
+		doc, err := fetchJSON(ctx, httpClient, endpoint)
+		if err != nil {
+			log.Printf("Failed to fetch data endpoint %s: %v", endpoint, err)
+			continue
+		}
+		data[endpoint] = doc
+	}
+	return data
+}
+
+// fetchJSON fetches a JSON document through the rate-limited client and decodes it
+func fetchJSON(ctx context.Context, httpClient *HTTPClient, endpoint string) (any, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	var doc any
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxEndpointBytes)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	return doc, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// etaWindow is how far back completions are considered when estimating crawl speed
+	etaWindow = time.Minute
+	// minETASamples is the number of completions needed before an estimate is given
+	minETASamples = 5
+)
+
+// CrawlProgress is reported to the progress callback after every completed URL
+type CrawlProgress struct {
+	// Completed counts finished URLs, including the Failed ones
+	Completed int
+	Failed    int
+	Pending   int
+	// ETA is the estimated time remaining, or zero while it is still unknown
+	ETA time.Duration
+}
+
+// WithProgress calls fn after every URL the crawler finishes, successful or not
+func WithProgress(fn func(CrawlProgress)) CrawlerOption {
+	return func(c *Crawler) {
+		c.onProgress = fn
+	}
+}
+
+// crawlTracker keeps the frontier size and recent completion times of a crawl
+type crawlTracker struct {
+	mu          sync.Mutex
+	pending     int
+	completions []time.Time
+}
+
+// reset starts tracking a new crawl with an empty frontier
+func (t *crawlTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending = 0
+	t.completions = nil
+}
+
+// add grows the frontier by n queued URLs
+func (t *crawlTracker) add(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending += n
+}
+
+// complete records a finished URL at now
+func (t *crawlTracker) complete(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending--
+	t.completions = append(t.completions, now)
+	t.trim(now)
+}
+
+// remaining returns the frontier size and estimated time to finish it
+func (t *crawlTracker) remaining(now time.Time) (int, time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.trim(now)
+
+	if t.pending <= 0 || len(t.completions) < minETASamples {
+		return t.pending, 0
+	}
+	elapsed := now.Sub(t.completions[0])
+	if elapsed <= 0 {
+		return t.pending, 0
+	}
+	perPage := elapsed / time.Duration(len(t.completions))
+	return t.pending, perPage * time.Duration(t.pending)
+}
+
+// trim drops completions that fall outside the rolling window
+func (t *crawlTracker) trim(now time.Time) {
+	cutoff := now.Add(-etaWindow)
+	i := 0
+	for i < len(t.completions) && t.completions[i].Before(cutoff) {
+		i++
+	}
+	t.completions = t.completions[i:]
+}
+
+// EstimatedTimeRemaining estimates how long the current crawl needs to finish its frontier,
+// based on the pages-per-second rate over the last minute. It returns zero while there are
+// too few samples to estimate, and once the crawl is done
+func (c *Crawler) EstimatedTimeRemaining() time.Duration {
+	_, eta := c.tracker.remaining(time.Now())
+	return eta
+}
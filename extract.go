@@ -0,0 +1,330 @@
+package main
+
+import (
+	"cmp"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// minMainContentLen is the minimum amount of text (in bytes) a block needs to be considered main content
+const minMainContentLen = 140
+
+// nonTextElements are elements whose contents never contribute to page text
+var nonTextElements = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"noscript": true,
+	"template": true,
+}
+
+// contentContainers are the elements considered as main content candidates
+var contentContainers = map[string]bool{
+	"article": true,
+	"main":    true,
+	"section": true,
+	"div":     true,
+	"td":      true,
+	"body":    true,
+}
+
+// headElements are the elements that can appear in a document <head>
+var headElements = map[string]bool{
+	"html":     true,
+	"head":     true,
+	"title":    true,
+	"meta":     true,
+	"link":     true,
+	"base":     true,
+	"style":    true,
+	"script":   true,
+	"noscript": true,
+	"template": true,
+}
+
+// extractPageInfo runs the document extractors and stores their results on info.
+// Body-dependent extractors are skipped in metadata-only mode
+func extractPageInfo(info *PageInfo, doc *html.Node, base *url.URL, header http.Header, opts FetchOptions) {
+	info.Meta = extractMeta(doc)
+	policies := append([]string(nil), header.Values("Content-Security-Policy")...)
+	info.CSP = parseCSP(append(policies, extractMetaCSP(doc)...))
+	info.Canonical = extractLinkRel(doc, base, "canonical")
+	info.ManifestURL = extractLinkRel(doc, base, "manifest")
+	info.Icons = extractIcons(doc, base)
+	info.FaviconURL = bestFavicon(info.Icons, base)
+	info.Headers = header.Clone()
+	info.Doctype, info.QuirksMode = extractDoctype(doc)
+	if opts.CollectWarnings {
+		info.Warnings = extractWarnings(doc, header)
+	}
+	if opts.MetadataOnly {
+		return
+	}
+
+	info.MainContent = extractMainContent(doc)
+	info.NoscriptText = extractNoscriptText(doc)
+	// Fall back to the noscript content on JavaScript-heavy pages with little visible text
+	if len(info.MainContent) < minMainContentLen && len(info.NoscriptText) > len(info.MainContent) {
+		info.MainContent = info.NoscriptText
+	}
+	if opts.TopWords >= 0 {
+		stopwords := opts.Stopwords
+		if stopwords == nil {
+			stopwords = DefaultStopwords
+		}
+		info.TopWords = extractWordFrequencies(info.MainContent, cmp.Or(opts.TopWords, defaultTopWords), stopwords)
+	}
+	info.Links = extractLinks(doc, base)
+	info.Iframes = extractIframes(doc, base)
+	info.DataEndpoints = extractDataEndpoints(doc, base, opts.DataAttributes)
+	info.Images, info.Pictures = extractImages(doc, base)
+	info.LanguageBreakdown = extractLanguageBreakdown(doc)
+	info.Times = extractTimes(doc)
+	info.ARIARoles = extractARIA(doc)
+	info.A11yWarnings = extractA11yWarnings(doc)
+	var counts resourceCounts
+	info.Assets, counts = extractAssets(doc, base)
+	info.NumScripts, info.NumStylesheets = counts.scripts, counts.stylesheets
+	info.NumImages, info.NumIframes = counts.images, counts.iframes
+	info.ClassNames = extractClassNames(doc)
+	info.Breakpoints = extractBreakpoints(doc)
+}
+
+// extractClassNames returns the distinct class names used in the document, in the order
+// they first appear
+func extractClassNames(n *html.Node) []string {
+	classes := newOrderedSet()
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for _, class := range strings.Fields(getAttr(n, "class")) {
+				classes.add(class)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return classes.list()
+}
+
+// readHead streams the document through the tokenizer and returns only the markup
+// up to the end of its <head>, so the body is never read or parsed
+func readHead(r io.Reader) ([]byte, error) {
+	var head []byte
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return head, nil
+			}
+			return nil, z.Err()
+		case html.StartTagToken, html.SelfClosingTagToken:
+			// Any element that cannot live in <head> implicitly starts the body
+			name, _ := z.TagName()
+			if !headElements[string(name)] {
+				return head, nil
+			}
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "head" {
+				return append(head, z.Raw()...), nil
+			}
+		}
+		head = append(head, z.Raw()...)
+	}
+}
+
+// extractMeta collects <meta> name/property values keyed by their lowercased name
+func extractMeta(n *html.Node) map[string]string {
+	meta := make(map[string]string)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "meta" {
+			key := getAttr(n, "name")
+			if key == "" {
+				key = getAttr(n, "property")
+			}
+			if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+				if _, exists := meta[key]; !exists {
+					meta[key] = strings.TrimSpace(getAttr(n, "content"))
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return meta
+}
+
+// extractLinkRel returns the absolute href of the first <link> whose rel contains rel
+func extractLinkRel(n *html.Node, base *url.URL, rel string) string {
+	if n.Type == html.ElementNode && n.Data == "link" && hasRel(n, rel) {
+		if href := resolveURL(base, getAttr(n, "href")); href != "" {
+			return href
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if href := extractLinkRel(c, base, rel); href != "" {
+			return href
+		}
+	}
+	return ""
+}
+
+// hasRel reports whether an element's space-separated rel attribute contains value
+func hasRel(n *html.Node, value string) bool {
+	for _, rel := range strings.Fields(getAttr(n, "rel")) {
+		if strings.EqualFold(rel, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// textContent returns the whitespace-normalized text of a subtree, skipping scripts and styles
+func textContent(n *html.Node) string {
+	var parts []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			parts = append(parts, strings.Fields(n.Data)...)
+			return
+		}
+		if n.Type == html.ElementNode && nonTextElements[n.Data] {
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(parts, " ")
+}
+
+// blockStats measures the text and markup contained in a subtree
+type blockStats struct {
+	text     int
+	linkText int
+	markup   int
+}
+
+// extractMainContent returns the text of the block that most likely holds the page's main content.
+// This is a heuristic: every container element with at least minMainContentLen bytes of text is
+// scored by its text-to-markup density (link text counts against it) weighted by its amount of
+// non-link text, and the highest scoring block wins. It is not always accurate, but it usually
+// leaves out navigation, footers and other boilerplate that plain body text includes
+func extractMainContent(n *html.Node) string {
+	var best *html.Node
+	bestScore := 0.0
+
+	var walk func(n *html.Node, inLink bool) blockStats
+	walk = func(n *html.Node, inLink bool) blockStats {
+		var s blockStats
+		switch n.Type {
+		case html.TextNode:
+			s.text = len(strings.TrimSpace(n.Data))
+			if inLink {
+				s.linkText = s.text
+			}
+			return s
+		case html.ElementNode:
+			if nonTextElements[n.Data] {
+				return s
+			}
+			// Approximate the bytes of the opening and closing tags
+			s.markup = 2*len(n.Data) + 5
+			for _, a := range n.Attr {
+				s.markup += len(a.Key) + len(a.Val) + 4
+			}
+			if n.Data == "a" {
+				inLink = true
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			cs := walk(c, inLink)
+			s.text += cs.text
+			s.linkText += cs.linkText
+			s.markup += cs.markup
+		}
+
+		if n.Type == html.ElementNode && contentContainers[n.Data] && s.text >= minMainContentLen {
+			plain := float64(s.text - s.linkText)
+			density := plain / float64(s.text+s.markup)
+			if score := density * plain; score > bestScore {
+				best, bestScore = n, score
+			}
+		}
+		return s
+	}
+	walk(n, false)
+
+	if best == nil {
+		return ""
+	}
+	return textContent(best)
+}
+
+// getAttr returns the value of the named attribute, or "" if it is absent
+func getAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// documentBase returns the URL that relative references resolve against,
+// honoring the first <base href> in the document
+func documentBase(doc *html.Node, pageURL *url.URL) *url.URL {
+	var href string
+	var walk func(n *html.Node) bool
+	walk = func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "base" {
+			if href = getAttr(n, "href"); href != "" {
+				return true
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if walk(c) {
+				return true
+			}
+		}
+		return false
+	}
+	walk(doc)
+
+	if href == "" {
+		return pageURL
+	}
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return pageURL
+	}
+	return pageURL.ResolveReference(ref)
+}
+
+// resolveURL resolves ref against base, returning "" for empty or unparsable references
+func resolveURL(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if base == nil {
+		return u.String()
+	}
+	return base.ResolveReference(u).String()
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Form is an HTML form with the values it would submit as-is, including hidden
+// fields such as CSRF tokens
+type Form struct {
+	ID     string
+	Action string // absolute URL the form submits to
+	Method string // uppercased, GET when unspecified
+	Fields url.Values
+}
+
+// ParseForms parses the document in r and returns its forms in document order.
+// Actions are resolved against baseURL, or the document's <base href> if present
+func ParseForms(r io.Reader, baseURL string) ([]Form, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	base = documentBase(doc, base)
+
+	var forms []Form
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "form" {
+			forms = append(forms, parseForm(n, base))
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return forms, nil
+}
+
+// parseForm collects a form's action, method and default field values
+func parseForm(form *html.Node, base *url.URL) Form {
+	f := Form{
+		ID:     getAttr(form, "id"),
+		Action: resolveURL(base, getAttr(form, "action")),
+		Method: strings.ToUpper(strings.TrimSpace(getAttr(form, "method"))),
+		Fields: make(url.Values),
+	}
+	if f.Action == "" {
+		f.Action = base.String()
+	}
+	if f.Method == "" {
+		f.Method = http.MethodGet
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			name := getAttr(n, "name")
+			switch {
+			case name == "" || hasAttr(n, "disabled"):
+			case n.Data == "input":
+				switch strings.ToLower(getAttr(n, "type")) {
+				case "submit", "button", "image", "reset", "file":
+					// Only sent when clicked or chosen, so they have no default value
+				case "checkbox", "radio":
+					if hasAttr(n, "checked") {
+						f.Fields.Add(name, inputValue(n))
+					}
+				default:
+					f.Fields.Add(name, getAttr(n, "value"))
+				}
+			case n.Data == "textarea":
+				f.Fields.Add(name, nodeText(n))
+			case n.Data == "select":
+				if value, ok := selectedOption(n); ok {
+					f.Fields.Add(name, value)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(form)
+	return f
+}
+
+// inputValue returns a checkbox or radio value, which defaults to "on"
+func inputValue(n *html.Node) string {
+	if hasAttr(n, "value") {
+		return getAttr(n, "value")
+	}
+	return "on"
+}
+
+// selectedOption returns the value of a <select>'s selected option, or of its first option
+func selectedOption(sel *html.Node) (string, bool) {
+	var first, selected *html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "option" {
+			if first == nil {
+				first = n
+			}
+			if selected == nil && hasAttr(n, "selected") {
+				selected = n
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(sel)
+
+	option := selected
+	if option == nil {
+		option = first
+	}
+	if option == nil {
+		return "", false
+	}
+	if hasAttr(option, "value") {
+		return getAttr(option, "value"), true
+	}
+	return strings.TrimSpace(nodeText(option)), true
+}
+
+// nodeText returns the raw text of an element's children
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+	}
+	return sb.String()
+}
+
+// PostForm performs a rate-limited POST of values as an URL-encoded form
+func (c *HTTPClient) PostForm(ctx context.Context, url string, values url.Values) (*http.Response, error) {
+	return c.Post(ctx, url, "application/x-www-form-urlencoded", strings.NewReader(values.Encode()))
+}
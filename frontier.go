@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/url"
+	"sync"
+)
+
+// crawlJob is a URL waiting to be crawled, with its link distance from the seeds
+type crawlJob struct {
+	url   string
+	depth int
+}
+
+// frontier is the queue of URLs shared by the crawl workers. It deduplicates URLs
+// by their normalized form and knows the crawl is finished when the queue is empty
+// and no worker is still processing a job that could discover more.
+// With a per-host cap, each host accepts at most that many URLs over the whole crawl
+type frontier struct {
+	mu         sync.Mutex
+	cond       *sync.Cond
+	queue      []crawlJob
+	visited    map[string]bool
+	perHost    map[string]int
+	maxPerHost int
+	active     int
+	closed     bool
+}
+
+// newFrontier creates an empty frontier. A positive maxPerHost caps the URLs queued per host
+func newFrontier(maxPerHost int) *frontier {
+	f := &frontier{
+		visited:    make(map[string]bool),
+		perHost:    make(map[string]int),
+		maxPerHost: maxPerHost,
+	}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+// push queues a job unless its URL was already seen or its host is at the cap,
+// and reports whether it was queued
+func (f *frontier) push(job crawlJob) bool {
+	key, host := job.url, ""
+	if normalized, err := NormalizeURL(job.url); err == nil {
+		key = normalized
+	}
+	if u, err := url.Parse(key); err == nil {
+		host = u.Host
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed || f.visited[key] {
+		return false
+	}
+	if f.maxPerHost > 0 && f.perHost[host] >= f.maxPerHost {
+		return false
+	}
+	f.visited[key] = true
+	f.perHost[host]++
+	f.queue = append(f.queue, job)
+	f.cond.Signal()
+	return true
+}
+
+// next blocks until a job is available. It returns false once the crawl is finished or closed
+func (f *frontier) next() (crawlJob, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.queue) == 0 && f.active > 0 && !f.closed {
+		f.cond.Wait()
+	}
+	if f.closed || len(f.queue) == 0 {
+		return crawlJob{}, false
+	}
+	job := f.queue[0]
+	f.queue = f.queue[1:]
+	f.active++
+	return job, true
+}
+
+// done marks a job returned by next as finished. Links it discovered must be pushed before calling done
+func (f *frontier) done() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.active--
+	if f.active == 0 && len(f.queue) == 0 {
+		f.cond.Broadcast()
+	}
+}
+
+// close stops handing out jobs and wakes every waiting worker
+func (f *frontier) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	f.cond.Broadcast()
+}
@@ -3,9 +3,16 @@ module release-test
 go 1.23.0
 
 require (
+	github.com/parquet-go/parquet-go v0.25.1
 	golang.org/x/crypto v0.10.0
 	golang.org/x/net v0.10.0
 	golang.org/x/time v0.11.0
 )
 
-require golang.org/x/sys v0.10.0 // indirect
+require (
+	github.com/andybalholm/brotli v1.1.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+)
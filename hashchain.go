@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Fingerprint returns a BLAKE2b-256 digest identifying the page's URL, title and content
+func (p *PageInfo) Fingerprint() string {
+	h, _ := blake2b.New256(nil)
+	h.Write([]byte(p.URL))
+	h.Write([]byte{0})
+	h.Write([]byte(p.Title))
+	h.Write([]byte{0})
+	h.Write([]byte(p.ContentHash))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ChainedRecord is one entry of a tamper-evident crawl log
+type ChainedRecord struct {
+	URL         string
+	Fingerprint string
+	Timestamp   time.Time
+	Chain       string
+}
+
+// HashChain links crawled pages into a tamper-evident log.
+// Each record's chain value is BLAKE2b-256 over the previous chain value, the record URL, the
+// page fingerprint and the record timestamp, so changing, reordering or dropping any record breaks every value after it
+type HashChain struct {
+	mu   sync.Mutex
+	head [blake2b.Size256]byte
+}
+
+// NewHashChain creates an empty chain starting from an all-zero genesis value
+func NewHashChain() *HashChain {
+	return &HashChain{}
+}
+
+// Append adds a page to the chain and returns the record to store
+func (h *HashChain) Append(info *PageInfo) ChainedRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	record := ChainedRecord{
+		URL:         info.URL,
+		Fingerprint: info.Fingerprint(),
+		Timestamp:   time.Now().UTC(),
+	}
+	h.head = chainLink(h.head, record)
+	record.Chain = hex.EncodeToString(h.head[:])
+	return record
+}
+
+// ChainHead returns the current chain value in hex
+func (h *HashChain) ChainHead() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return hex.EncodeToString(h.head[:])
+}
+
+// Verify reports whether records form a valid chain ending at this chain's head.
+// It only succeeds against the HashChain that produced the records; use VerifyChain
+// to audit a log that was reloaded after a restart
+func (h *HashChain) Verify(records []ChainedRecord) bool {
+	return VerifyChain(records, h.ChainHead())
+}
+
+// VerifyChain recomputes the chain from the genesis value and reports whether every
+// record links to the previous one. If head is not empty, the last record must also
+// match it, which detects records dropped from the end of the log; head is then the
+// ChainHead value kept separately from the records
+func VerifyChain(records []ChainedRecord, head string) bool {
+	var prev [blake2b.Size256]byte
+	for _, record := range records {
+		prev = chainLink(prev, record)
+		if hex.EncodeToString(prev[:]) != record.Chain {
+			return false
+		}
+	}
+	return head == "" || hex.EncodeToString(prev[:]) == head
+}
+
+// chainLink computes the chain value of record following prev
+func chainLink(prev [blake2b.Size256]byte, record ChainedRecord) [blake2b.Size256]byte {
+	var nanos [8]byte
+	binary.BigEndian.PutUint64(nanos[:], uint64(record.Timestamp.UnixNano()))
+
+	h, _ := blake2b.New256(nil)
+	h.Write(prev[:])
+	h.Write([]byte(record.URL))
+	h.Write([]byte{0})
+	h.Write([]byte(record.Fingerprint))
+	h.Write(nanos[:])
+
+	var next [blake2b.Size256]byte
+	copy(next[:], h.Sum(nil))
+	return next
+}
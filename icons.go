@@ -0,0 +1,91 @@
+package main
+
+import (
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// iconRels are the link relations that declare page icons
+var iconRels = []string{"icon", "apple-touch-icon", "apple-touch-icon-precomposed", "mask-icon"}
+
+// Icon is an icon declared by a <link> element
+type Icon struct {
+	URL   string
+	Rel   string
+	Sizes string // e.g. "16x16 32x32" or "any"
+	Type  string
+	Media string // e.g. "(prefers-color-scheme: dark)"
+}
+
+// extractIcons returns every icon declared in the document with its URL resolved
+func extractIcons(n *html.Node, base *url.URL) []Icon {
+	var icons []Icon
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "link" {
+			for _, rel := range iconRels {
+				if !hasRel(n, rel) {
+					continue
+				}
+				if href := resolveURL(base, getAttr(n, "href")); href != "" {
+					icons = append(icons, Icon{
+						URL:   href,
+						Rel:   rel,
+						Sizes: strings.TrimSpace(getAttr(n, "sizes")),
+						Type:  strings.TrimSpace(getAttr(n, "type")),
+						Media: strings.TrimSpace(getAttr(n, "media")),
+					})
+				}
+				break
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return icons
+}
+
+// bestFavicon picks the icon a browser tab would most likely show: the largest rel="icon"
+// not limited to dark mode, then any declared icon, then /favicon.ico on the page's host
+func bestFavicon(icons []Icon, base *url.URL) string {
+	best, bestSize := "", -1
+	for _, icon := range icons {
+		if icon.Rel != "icon" || strings.Contains(strings.ToLower(icon.Media), "dark") {
+			continue
+		}
+		if size := iconSize(icon.Sizes); size > bestSize {
+			best, bestSize = icon.URL, size
+		}
+	}
+	switch {
+	case best != "":
+		return best
+	case len(icons) > 0:
+		return icons[0].URL
+	case base != nil:
+		return resolveURL(base, "/favicon.ico")
+	}
+	return ""
+}
+
+// iconSize returns the largest width in a sizes attribute, treating "any" (scalable) as largest
+// and a missing or unparsable value as zero
+func iconSize(sizes string) int {
+	largest := 0
+	for _, size := range strings.Fields(strings.ToLower(sizes)) {
+		if size == "any" {
+			return math.MaxInt
+		}
+		width, _, _ := strings.Cut(size, "x")
+		if w, err := strconv.Atoi(width); err == nil && w > largest {
+			largest = w
+		}
+	}
+	return largest
+}
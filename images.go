@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ImageCandidate is one entry of a srcset attribute
+type ImageCandidate struct {
+	URL        string
+	Descriptor string
+}
+
+// Image describes an <img> element with absolute URLs
+type Image struct {
+	URL    string
+	Alt    string
+	Srcset []ImageCandidate
+}
+
+// PictureSource describes a <source> element inside a <picture>
+type PictureSource struct {
+	Srcset []ImageCandidate
+	Media  string
+	Type   string
+}
+
+// Picture describes a <picture> element: its responsive sources and fallback image
+type Picture struct {
+	Sources  []PictureSource
+	Fallback *Image
+}
+
+// extractImages collects every <img> and <picture> in the document, resolving URLs against base.
+// Fallback images inside a <picture> are reported both in images and on their picture
+func extractImages(n *html.Node, base *url.URL) (images []Image, pictures []Picture) {
+	var walk func(n *html.Node, picture *Picture)
+	walk = func(n *html.Node, picture *Picture) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "picture":
+				p := &Picture{}
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					walk(c, p)
+				}
+				pictures = append(pictures, *p)
+				return
+			case "source":
+				if picture != nil {
+					picture.Sources = append(picture.Sources, PictureSource{
+						Srcset: parseSrcset(getAttr(n, "srcset"), base),
+						Media:  getAttr(n, "media"),
+						Type:   getAttr(n, "type"),
+					})
+				}
+			case "img":
+				img := Image{
+					URL:    resolveURL(base, getAttr(n, "src")),
+					Alt:    getAttr(n, "alt"),
+					Srcset: parseSrcset(getAttr(n, "srcset"), base),
+				}
+				images = append(images, img)
+				if picture != nil && picture.Fallback == nil {
+					picture.Fallback = &img
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, picture)
+		}
+	}
+	walk(n, nil)
+	return images, pictures
+}
+
+// parseSrcset splits a srcset attribute into candidates with absolute URLs.
+// Candidates are separated by commas, so URLs that themselves contain commas are not supported
+func parseSrcset(srcset string, base *url.URL) []ImageCandidate {
+	var candidates []ImageCandidate
+	for _, part := range strings.Split(srcset, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		u := resolveURL(base, fields[0])
+		if u == "" {
+			continue
+		}
+		candidates = append(candidates, ImageCandidate{
+			URL:        u,
+			Descriptor: strings.Join(fields[1:], " "),
+		})
+	}
+	return candidates
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// extractLanguageBreakdown reports the share of page text written in each language.
+// Text belongs to the language of its nearest ancestor with a lang attribute; text outside
+// any lang scope is not counted, so the proportions sum to 1 over the language-tagged text
+func extractLanguageBreakdown(n *html.Node) map[string]float64 {
+	lengths := make(map[string]int)
+	total := 0
+
+	var walk func(n *html.Node, lang string)
+	walk = func(n *html.Node, lang string) {
+		switch n.Type {
+		case html.TextNode:
+			if lang != "" {
+				l := len(strings.TrimSpace(n.Data))
+				lengths[lang] += l
+				total += l
+			}
+			return
+		case html.ElementNode:
+			if nonTextElements[n.Data] {
+				return
+			}
+			for _, a := range n.Attr {
+				if a.Key == "lang" {
+					lang = strings.ToLower(strings.TrimSpace(a.Val))
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, lang)
+		}
+	}
+	walk(n, "")
+
+	breakdown := make(map[string]float64)
+	if total == 0 {
+		return breakdown
+	}
+	for lang, l := range lengths {
+		if l > 0 {
+			breakdown[lang] = float64(l) / float64(total)
+		}
+	}
+	return breakdown
+}
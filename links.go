@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// orderedSet is a set of strings that remembers insertion order,
+// giving list-producing extractors deduplicated output in first-seen order
+type orderedSet struct {
+	seen  map[string]bool
+	items []string
+}
+
+// newOrderedSet creates an empty ordered set
+func newOrderedSet() *orderedSet {
+	return &orderedSet{seen: make(map[string]bool)}
+}
+
+// add inserts item if it is not already present and reports whether it was added
+func (s *orderedSet) add(item string) bool {
+	if s.seen[item] {
+		return false
+	}
+	s.seen[item] = true
+	s.items = append(s.items, item)
+	return true
+}
+
+// list returns the items in insertion order
+func (s *orderedSet) list() []string {
+	return s.items
+}
+
+// extractLinks returns the absolute http(s) URLs of the document's <a> and <area> links,
+// without fragments, deduplicated in the order they first appear
+func extractLinks(n *html.Node, base *url.URL) []string {
+	links := newOrderedSet()
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.Data == "a" || n.Data == "area") {
+			if link := resolveLink(base, getAttr(n, "href")); link != "" {
+				links.add(link)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return links.list()
+}
+
+// extractIframes returns the resolved sources of the document's <iframe> elements,
+// deduplicated in document order. Empty sources and about: URLs such as about:blank are skipped
+func extractIframes(n *html.Node, base *url.URL) []string {
+	frames := newOrderedSet()
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "iframe" {
+			src := strings.TrimSpace(getAttr(n, "src"))
+			if !strings.HasPrefix(strings.ToLower(src), "about:") {
+				if frame := resolveURL(base, src); frame != "" {
+					frames.add(frame)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return frames.list()
+}
+
+// resolveLink resolves an href to an absolute http(s) URL without its fragment,
+// returning "" for empty, unparsable or non-web links such as mailto: and javascript:
+func resolveLink(base *url.URL, href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") {
+		return ""
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	u := ref
+	if base != nil {
+		u = base.ResolveReference(ref)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String()
+}
+
+// ExtractLinksStream extracts the same links as the DOM-based extractor while streaming
+// the document through the tokenizer, so the tree is never built. Hrefs are resolved once
+// the stream ends, because the first <base href> applies to the whole document
+func ExtractLinksStream(r io.Reader, baseURL string) ([]string, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base URL: %w", err)
+	}
+
+	var (
+		hrefs    []string
+		baseHref string
+	)
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to tokenize HTML: %w", z.Err())
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+
+		name, hasAttr := z.TagName()
+		tag := string(name)
+		if !hasAttr || (tag != "a" && tag != "area" && tag != "base") {
+			continue
+		}
+		for {
+			key, val, more := z.TagAttr()
+			if string(key) == "href" {
+				if tag != "base" {
+					hrefs = append(hrefs, string(val))
+				} else if baseHref == "" {
+					baseHref = string(val)
+				}
+				break
+			}
+			if !more {
+				break
+			}
+		}
+	}
+
+	// Honor <base href> the same way documentBase does
+	if baseHref = strings.TrimSpace(baseHref); baseHref != "" {
+		if ref, err := url.Parse(baseHref); err == nil {
+			base = base.ResolveReference(ref)
+		}
+	}
+
+	links := newOrderedSet()
+	for _, href := range hrefs {
+		if link := resolveLink(base, href); link != "" {
+			links.add(link)
+		}
+	}
+	return links.list(), nil
+}
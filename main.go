@@ -1,10 +1,13 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strings"
@@ -17,6 +20,16 @@ import (
 	"golang.org/x/time/rate"
 )
 
+const (
+	// defaultSaltLength is the size in bytes of the salts generated by NewCryptoUtils
+	defaultSaltLength = 16
+	// minSaltLength is the shortest salt accepted by NewCryptoUtilsSaltLen and ValidateSalt
+	minSaltLength = 8
+	// pbkdf2Iterations and pbkdf2KeyLen are the PBKDF2 parameters used by HashTitle
+	pbkdf2Iterations = 10000
+	pbkdf2KeyLen     = 32
+)
+
 // CryptoUtils provides cryptographic utilities for web content
 type CryptoUtils struct {
 	salt []byte
@@ -24,33 +37,105 @@ type CryptoUtils struct {
 
 // NewCryptoUtils creates a new CryptoUtils instance with random salt
 func NewCryptoUtils() (*CryptoUtils, error) {
-	salt := make([]byte, 16)
+	return NewCryptoUtilsSaltLen(defaultSaltLength)
+}
+
+// NewCryptoUtilsSaltLen creates a new CryptoUtils instance with a random salt of n bytes.
+// n must be at least minSaltLength
+func NewCryptoUtilsSaltLen(n int) (*CryptoUtils, error) {
+	if n < minSaltLength {
+		return nil, fmt.Errorf("salt length %d is too short, need at least %d bytes", n, minSaltLength)
+	}
+	salt := make([]byte, n)
 	if _, err := rand.Read(salt); err != nil {
 		return nil, fmt.Errorf("failed to generate salt: %w", err)
 	}
 	return &CryptoUtils{salt: salt}, nil
 }
 
-// HashTitle computes multiple hash values for the given title
-func (c *CryptoUtils) HashTitle(title string) (map[string]string, error) {
-	hashes := make(map[string]string)
+// hashAlgorithms lists the digests computed by HashTitle
+var hashAlgorithms = []string{"sha3-256", "blake2b-256", "pbkdf2-sha3"}
+
+// pbkdf2PRF names the pseudorandom function used for PBKDF2
+const pbkdf2PRF = "sha3-256"
 
-	// SHA3-256 hash
-	sha3Hash := sha3.Sum256([]byte(title))
-	hashes["sha3-256"] = hex.EncodeToString(sha3Hash[:])
+// HashResult is a self-describing digest. Besides the hex-encoded digest it records the
+// algorithm and every parameter used to compute it, so a stored result can be verified
+// even after the defaults have changed. The KDF fields are only set for pbkdf2-sha3
+type HashResult struct {
+	Algorithm string
+	Digest    string
 
-	// BLAKE2b hash
-	blake2bHash := blake2b.Sum256([]byte(title))
-	hashes["blake2b-256"] = hex.EncodeToString(blake2bHash[:])
+	Salt       string // hex-encoded
+	SaltLen    int
+	Iterations int
+	KeyLen     int
+	PRF        string
+}
 
-	// PBKDF2 key derivation (for demonstration)
-	pbkdf2Key := pbkdf2.Key([]byte(title), c.salt, 10000, 32, sha3.New256)
-	hashes["pbkdf2-sha3"] = hex.EncodeToString(pbkdf2Key)
-	hashes["salt"] = hex.EncodeToString(c.salt)
+// HashTitle computes multiple hash values for the given title, keyed by algorithm
+func (c *CryptoUtils) HashTitle(title string) (map[string]HashResult, error) {
+	hashes := make(map[string]HashResult)
+	for _, algo := range hashAlgorithms {
+		result, err := c.hash(algo, []byte(title))
+		if err != nil {
+			return nil, err
+		}
+		hashes[algo] = result
+	}
 
 	return hashes, nil
 }
 
+// hash computes one of the HashTitle algorithms over data with the current parameters
+func (c *CryptoUtils) hash(algo string, data []byte) (HashResult, error) {
+	result := HashResult{Algorithm: algo}
+	if algo == "pbkdf2-sha3" {
+		result.Salt = hex.EncodeToString(c.salt)
+		result.SaltLen = len(c.salt)
+		result.Iterations = pbkdf2Iterations
+		result.KeyLen = pbkdf2KeyLen
+		result.PRF = pbkdf2PRF
+	}
+
+	digest, err := result.compute(data)
+	if err != nil {
+		return HashResult{}, err
+	}
+	result.Digest = digest
+	return result, nil
+}
+
+// compute recomputes the digest over data using only the parameters recorded in r
+func (r HashResult) compute(data []byte) (string, error) {
+	switch r.Algorithm {
+	case "sha3-256":
+		// SHA3-256 hash
+		sha3Hash := sha3.Sum256(data)
+		return hex.EncodeToString(sha3Hash[:]), nil
+	case "blake2b-256":
+		// BLAKE2b hash
+		blake2bHash := blake2b.Sum256(data)
+		return hex.EncodeToString(blake2bHash[:]), nil
+	case "pbkdf2-sha3":
+		// PBKDF2 key derivation (for demonstration)
+		salt, err := r.decodeSalt()
+		if err != nil {
+			return "", err
+		}
+		if r.PRF != pbkdf2PRF {
+			return "", fmt.Errorf("unsupported PBKDF2 PRF: %q", r.PRF)
+		}
+		if r.Iterations < 1 || r.KeyLen < 1 {
+			return "", fmt.Errorf("invalid PBKDF2 parameters: %d iterations, key length %d", r.Iterations, r.KeyLen)
+		}
+		pbkdf2Key := pbkdf2.Key(data, salt, r.Iterations, r.KeyLen, sha3.New256)
+		return hex.EncodeToString(pbkdf2Key), nil
+	default:
+		return "", fmt.Errorf("unknown hash algorithm: %s", r.Algorithm)
+	}
+}
+
 // ValidateContentIntegrity compares content hash with expected value
 func (c *CryptoUtils) ValidateContentIntegrity(content, expectedHash string) bool {
 	hash := blake2b.Sum256([]byte(content))
@@ -58,28 +143,61 @@ func (c *CryptoUtils) ValidateContentIntegrity(content, expectedHash string) boo
 	return actualHash == expectedHash
 }
 
+// hashContent computes the BLAKE2b-256 digest used to identify page content
+func hashContent(data []byte) string {
+	hash := blake2b.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
 // HTTPClient wraps http.Client with rate limiting functionality
 type HTTPClient struct {
-	client  *http.Client
-	limiter *rate.Limiter
+	client             *http.Client
+	transport          *http.Transport
+	transportOpts      transportOptions
+	transportErr       error
+	limiter            *rate.Limiter
+	bandwidth          *rate.Limiter
+	validators         *validatorCache
+	userAgents         *uaRotator
+	hooks              []RequestHook
+	middleware         []Middleware
+	shouldRetry        ShouldRetryFunc
+	idempotentRetries  bool
+	metrics            func(RequestMetrics)
+	redirectBudget     time.Duration
+	allowRedirectLoops bool
+	doh                *dohResolver
+	digestAuth         *digestAuth
+	downloadProgress   func(DownloadProgress)
 }
 
 // NewHTTPClient creates a new HTTP client with rate limiting
 // limit: requests per second, burst: maximum burst size
-func NewHTTPClient(limit rate.Limit, burst int) *HTTPClient {
-	return &HTTPClient{
-		client:  &http.Client{Timeout: 30 * time.Second},
-		limiter: rate.NewLimiter(limit, burst),
+func NewHTTPClient(limit rate.Limit, burst int, opts ...ClientOption) *HTTPClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	c := &HTTPClient{
+		client:     &http.Client{Timeout: 30 * time.Second, Transport: transport},
+		transport:  transport,
+		limiter:    rate.NewLimiter(limit, burst),
+		validators: newValidatorCache(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.client.CheckRedirect = c.checkRedirect
+	c.setupTransport()
+
+	// DoH queries use a plain client sharing this client's rate limiter
+	if c.doh != nil {
+		c.doh.client = NewHTTPClient(limit, burst)
+		c.doh.client.limiter = c.limiter
+		c.transport.DialContext = c.doh.dialContext
 	}
+	return c
 }
 
 // Get performs a rate-limited HTTP GET request
 func (c *HTTPClient) Get(ctx context.Context, url string) (*http.Response, error) {
-	// Wait for rate limiter permission
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limiter error: %w", err)
-	}
-
 	// Create request with context
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -87,14 +205,102 @@ func (c *HTTPClient) Get(ctx context.Context, url string) (*http.Response, error
 	}
 
 	// Perform the request
-	return c.client.Do(req)
+	return c.Do(req)
 }
 
+// ErrBodyTooShort is returned when a response body is shorter than FetchOptions.MinBodyBytes
+var ErrBodyTooShort = errors.New("response body too short")
+
 // PageInfo contains information about a fetched page
 type PageInfo struct {
-	URL    string
-	Title  string
-	Hashes map[string]string
+	URL          string
+	StatusCode   int
+	Title        string
+	Doctype      string
+	QuirksMode   bool
+	Hashes       map[string]HashResult
+	ContentHash  string
+	Headers      http.Header
+	Trailers     http.Header
+	Meta         map[string]string
+	Canonical    string
+	CSP          map[string][]string
+	ManifestURL  string
+	Manifest     map[string]any
+	FaviconURL   string
+	Icons        []Icon
+	MainContent  string
+	NoscriptText string
+	Links        []string
+	Iframes      []string
+	Images       []Image
+	Pictures     []Picture
+	Assets       []Asset
+	ClassNames   []string
+	Breakpoints  []string
+	Redirects    []RedirectHop
+	Aliases      []string
+	Times        []TimeMention
+
+	DataEndpoints     []string
+	EndpointData      map[string]any
+	LanguageBreakdown map[string]float64
+	TopWords          []WordCount
+	ARIARoles         map[string]int
+	A11yWarnings      []string
+	IntegrityFailures []string
+	Warnings          []string
+
+	NumScripts     int
+	NumStylesheets int
+	NumImages      int
+	NumIframes     int
+}
+
+// FetchOptions controls how much of each page is read and extracted
+type FetchOptions struct {
+	// MetadataOnly stops reading the page after its <head>. Only URL, StatusCode, Title,
+	// Doctype, QuirksMode, Hashes, Headers, Meta, Canonical, CSP, icon and manifest fields are
+	// populated; fields derived from the body, such as ContentHash, Trailers, MainContent,
+	// Links and Images, are left empty
+	MetadataOnly bool
+
+	// MinBodyBytes treats responses with a shorter body as failures with ErrBodyTooShort,
+	// catching soft errors and placeholder pages. Zero disables the check, and it does
+	// not apply in MetadataOnly mode since the body is not read
+	MinBodyBytes int
+
+	// FetchManifest fetches and parses the web app manifest into PageInfo.Manifest.
+	// The extra request goes through the same rate-limited client
+	FetchManifest bool
+
+	// InvalidTitleUTF8 selects how invalid UTF-8 in the page title is handled.
+	// The default replaces invalid sequences with U+FFFD
+	InvalidTitleUTF8 InvalidUTF8Mode
+
+	// TopWords is how many of the most frequent words of the main content are kept in
+	// PageInfo.TopWords. Zero keeps 20 and a negative value disables the analysis
+	TopWords int
+
+	// Stopwords replaces DefaultStopwords as the words left out of PageInfo.TopWords
+	Stopwords map[string]bool
+
+	// DataAttributes limits DataEndpoints extraction to these data-* attribute names,
+	// such as "data-api-url". Empty inspects every data-* attribute
+	DataAttributes []string
+
+	// FetchDataEndpoints fetches every URL in PageInfo.DataEndpoints as JSON into
+	// PageInfo.EndpointData. The extra requests go through the same rate-limited client
+	FetchDataEndpoints bool
+
+	// VerifyIntegrity fetches every script and stylesheet that declares an integrity
+	// attribute and checks it, reporting failures in PageInfo.IntegrityFailures.
+	// The extra requests go through the same rate-limited client
+	VerifyIntegrity bool
+
+	// CollectWarnings records data-quality problems noticed during extraction, such as a
+	// missing title or multiple canonical links, in PageInfo.Warnings
+	CollectWarnings bool
 }
 
 // fetchAndParseHTML fetches HTML content from the given URL and extracts title
@@ -108,6 +314,16 @@ func fetchAndParseHTML(url string, crypto *CryptoUtils) (*PageInfo, error) {
 
 	fmt.Println("等待限流器许可...")
 
+	return fetchPage(ctx, httpClient, crypto, url, FetchOptions{})
+}
+
+// fetchPage fetches and parses a single page using the given client.
+// The whole fetch, including reading the body, is bound to ctx
+func fetchPage(ctx context.Context, httpClient *HTTPClient, crypto *CryptoUtils, url string, opts FetchOptions) (*PageInfo, error) {
+	// Record the redirect chain followed for this page
+	redirects := &redirectTrace{}
+	ctx = withRedirectTrace(ctx, redirects)
+
 	// Fetch the webpage content with rate limiting
 	resp, err := httpClient.Get(ctx, url)
 	if err != nil {
@@ -115,14 +331,32 @@ func fetchAndParseHTML(url string, crypto *CryptoUtils) (*PageInfo, error) {
 	}
 	defer resp.Body.Close()
 
+	// Read the full body so the content can be hashed as well as parsed,
+	// or just the <head> when only metadata is wanted
+	var body []byte
+	if opts.MetadataOnly {
+		body, err = readHead(resp.Body)
+	} else {
+		body, err = io.ReadAll(resp.Body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if !opts.MetadataOnly && len(body) < opts.MinBodyBytes {
+		return nil, fmt.Errorf("%w: got %d bytes, want at least %d", ErrBodyTooShort, len(body), opts.MinBodyBytes)
+	}
+
 	// Parse the HTML content
-	doc, err := html.Parse(resp.Body)
+	doc, err := html.Parse(bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse HTML: %w", err)
 	}
 
 	// Extract the title from the parsed HTML
-	title := extractTitle(doc)
+	title, err := sanitizeTitle(extractTitle(doc), opts.InvalidTitleUTF8)
+	if err != nil {
+		return nil, err
+	}
 
 	// Compute cryptographic hashes for the title
 	hashes, err := crypto.HashTitle(title)
@@ -130,11 +364,37 @@ func fetchAndParseHTML(url string, crypto *CryptoUtils) (*PageInfo, error) {
 		return nil, fmt.Errorf("failed to compute hashes: %w", err)
 	}
 
-	return &PageInfo{
-		URL:    url,
-		Title:  title,
-		Hashes: hashes,
-	}, nil
+	info := &PageInfo{
+		URL:        url,
+		StatusCode: resp.StatusCode,
+		Title:      title,
+		Hashes:     hashes,
+		Redirects:  redirects.list(),
+	}
+	if !opts.MetadataOnly {
+		info.ContentHash = hashContent(body)
+		// Trailers are only available once the body has been read to EOF
+		if len(resp.Trailer) > 0 {
+			info.Trailers = resp.Trailer.Clone()
+		}
+	}
+	extractPageInfo(info, doc, documentBase(doc, resp.Request.URL), resp.Header, opts)
+
+	if opts.FetchManifest && info.ManifestURL != "" {
+		manifest, err := fetchManifest(ctx, httpClient, info.ManifestURL)
+		if err != nil {
+			log.Printf("Failed to fetch manifest %s: %v", info.ManifestURL, err)
+		}
+		info.Manifest = manifest
+	}
+	if opts.VerifyIntegrity {
+		info.IntegrityFailures = checkSubresources(ctx, httpClient, info.Assets)
+	}
+	if opts.FetchDataEndpoints && len(info.DataEndpoints) > 0 {
+		info.EndpointData = fetchDataEndpoints(ctx, httpClient, info.DataEndpoints)
+	}
+
+	return info, nil
 }
 
 // extractTitle traverses the HTML tree to find the title element
@@ -169,8 +429,8 @@ func printPageInfo(info *PageInfo) {
 	if info.Title != "" {
 		fmt.Printf("标题: %s\n", info.Title)
 		fmt.Println("加密哈希值:")
-		for hashType, hashValue := range info.Hashes {
-			fmt.Printf("  %s: %s\n", hashType, hashValue)
+		for hashType, hash := range info.Hashes {
+			fmt.Printf("  %s: %s\n", hashType, hash.Digest)
 		}
 	} else {
 		fmt.Println("未找到网页标题")
@@ -206,7 +466,7 @@ func main() {
 
 		// Demonstrate content integrity validation
 		if pageInfo.Title != "" {
-			isValid := crypto.ValidateContentIntegrity(pageInfo.Title, pageInfo.Hashes["blake2b-256"])
+			isValid := crypto.ValidateContentIntegrity(pageInfo.Title, pageInfo.Hashes["blake2b-256"].Digest)
 			fmt.Printf("内容完整性验证: %v\n", isValid)
 		}
 
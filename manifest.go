@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// maxManifestBytes caps the size of a web app manifest that will be parsed
+const maxManifestBytes = 1 << 20
+
+// fetchManifest fetches a web app manifest through the rate-limited client and decodes it
+func fetchManifest(ctx context.Context, httpClient *HTTPClient, manifestURL string) (map[string]any, error) {
+	resp, err := httpClient.Get(ctx, manifestURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	var manifest map[string]any
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestBytes)).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	return manifest, nil
+}
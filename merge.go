@@ -0,0 +1,120 @@
+package main
+
+import (
+	"cmp"
+	"maps"
+)
+
+// MergePageInfo combines two crawls of the same page into a new PageInfo, filling in
+// fields the newer crawl left empty from the older one. The rules are:
+//   - scalars such as Title, StatusCode and the resource counts: newer wins unless zero
+//   - Meta: merged per key, newer wins for keys present in both
+//   - Links and Aliases: union of both, in older order followed by entries only the
+//     newer crawl found
+//   - Redirects: always taken from newer, since they describe that fetch's timing
+//   - Doctype, QuirksMode, Warnings and IntegrityFailures: always taken from newer,
+//     since their absence is meaningful
+//   - every other map or slice: newer wins unless empty, keeping values such as
+//     Hashes or Images consistent with a single crawl
+//
+// Either argument may be nil. The inputs are not modified, but the result may share
+// maps and slices with them
+func MergePageInfo(older, newer *PageInfo) *PageInfo {
+	if older == nil && newer == nil {
+		return nil
+	}
+	if older == nil {
+		merged := *newer
+		return &merged
+	}
+	if newer == nil {
+		merged := *older
+		return &merged
+	}
+
+	return &PageInfo{
+		URL:          cmp.Or(newer.URL, older.URL),
+		StatusCode:   cmp.Or(newer.StatusCode, older.StatusCode),
+		Title:        cmp.Or(newer.Title, older.Title),
+		Doctype:      newer.Doctype,
+		QuirksMode:   newer.QuirksMode,
+		Hashes:       newerMap(older.Hashes, newer.Hashes),
+		ContentHash:  cmp.Or(newer.ContentHash, older.ContentHash),
+		Headers:      newerMap(older.Headers, newer.Headers),
+		Trailers:     newerMap(older.Trailers, newer.Trailers),
+		Meta:         mergeMeta(older.Meta, newer.Meta),
+		Canonical:    cmp.Or(newer.Canonical, older.Canonical),
+		CSP:          newerMap(older.CSP, newer.CSP),
+		ManifestURL:  cmp.Or(newer.ManifestURL, older.ManifestURL),
+		Manifest:     newerMap(older.Manifest, newer.Manifest),
+		FaviconURL:   cmp.Or(newer.FaviconURL, older.FaviconURL),
+		Icons:        newerSlice(older.Icons, newer.Icons),
+		MainContent:  cmp.Or(newer.MainContent, older.MainContent),
+		NoscriptText: cmp.Or(newer.NoscriptText, older.NoscriptText),
+		Links:        mergeLinks(older.Links, newer.Links),
+		Iframes:      newerSlice(older.Iframes, newer.Iframes),
+		Images:       newerSlice(older.Images, newer.Images),
+		Pictures:     newerSlice(older.Pictures, newer.Pictures),
+		Assets:       newerSlice(older.Assets, newer.Assets),
+		ClassNames:   newerSlice(older.ClassNames, newer.ClassNames),
+		Breakpoints:  newerSlice(older.Breakpoints, newer.Breakpoints),
+		Redirects:    newer.Redirects,
+		Aliases:      mergeLinks(older.Aliases, newer.Aliases),
+		Times:        newerSlice(older.Times, newer.Times),
+
+		DataEndpoints:     newerSlice(older.DataEndpoints, newer.DataEndpoints),
+		EndpointData:      newerMap(older.EndpointData, newer.EndpointData),
+		LanguageBreakdown: newerMap(older.LanguageBreakdown, newer.LanguageBreakdown),
+		TopWords:          newerSlice(older.TopWords, newer.TopWords),
+		ARIARoles:         newerMap(older.ARIARoles, newer.ARIARoles),
+		A11yWarnings:      newerSlice(older.A11yWarnings, newer.A11yWarnings),
+		IntegrityFailures: newer.IntegrityFailures,
+		Warnings:          newer.Warnings,
+
+		NumScripts:     cmp.Or(newer.NumScripts, older.NumScripts),
+		NumStylesheets: cmp.Or(newer.NumStylesheets, older.NumStylesheets),
+		NumImages:      cmp.Or(newer.NumImages, older.NumImages),
+		NumIframes:     cmp.Or(newer.NumIframes, older.NumIframes),
+	}
+}
+
+// newerMap returns newer unless it is empty
+func newerMap[M ~map[K]V, K comparable, V any](older, newer M) M {
+	if len(newer) > 0 {
+		return newer
+	}
+	return older
+}
+
+// newerSlice returns newer unless it is empty
+func newerSlice[S ~[]E, E any](older, newer S) S {
+	if len(newer) > 0 {
+		return newer
+	}
+	return older
+}
+
+// mergeMeta combines two meta maps, preferring newer values for shared keys
+func mergeMeta(older, newer map[string]string) map[string]string {
+	if len(older) == 0 || len(newer) == 0 {
+		return newerMap(older, newer)
+	}
+	merged := maps.Clone(older)
+	maps.Copy(merged, newer)
+	return merged
+}
+
+// mergeLinks returns the union of both link lists in first-seen order
+func mergeLinks(older, newer []string) []string {
+	if len(older) == 0 || len(newer) == 0 {
+		return newerSlice(older, newer)
+	}
+	set := newOrderedSet()
+	for _, link := range older {
+		set.add(link)
+	}
+	for _, link := range newer {
+		set.add(link)
+	}
+	return set.list()
+}
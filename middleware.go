@@ -0,0 +1,38 @@
+package main
+
+import "net/http"
+
+// RoundTripFunc executes a request and returns its response
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps request execution, for example to add logging, caching or auth.
+// It receives the next step of the chain and returns a replacement that may inspect or
+// modify the request, short-circuit it with its own response, or post-process the result.
+// A response returned without Request has it set to the request passed to Do
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// WithMiddleware adds middleware to the client, as Use does
+func WithMiddleware(mw ...Middleware) ClientOption {
+	return func(c *HTTPClient) {
+		c.Use(mw...)
+	}
+}
+
+// Use adds middleware around request execution in Do. Middleware added first is the
+// outermost: it sees the request first and the response last. The chain runs after
+// user-agent rotation, request hooks and body compression, and wraps the built-in rate
+// limiting, retries, redirects and digest auth as a single step, so each middleware sees
+// one call per Do regardless of how many attempts were made. Use must not be called
+// concurrently with requests
+func (c *HTTPClient) Use(mw ...Middleware) {
+	c.middleware = append(c.middleware, mw...)
+}
+
+// roundTrip runs req through the middleware chain and the built-in request execution
+func (c *HTTPClient) roundTrip(req *http.Request) (*http.Response, error) {
+	next := RoundTripFunc(c.doWithDigestAuth)
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		next = c.middleware[i](next)
+	}
+	return next(req)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrMalformedRecord is reported for an NDJSON line that does not decode as a PageInfo
+var ErrMalformedRecord = errors.New("malformed record")
+
+// maxQueuedStreamErrors bounds the malformed-line errors ReadPageInfoStream holds while the
+// caller is busy reading pages
+const maxQueuedStreamErrors = 100
+
+// gzipMagic is the header every gzip stream starts with
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ReadPageInfoStream decodes newline-delimited JSON PageInfo records from r, one per line,
+// without loading the whole input. Gzip-compressed input is detected from its magic bytes
+// and decompressed transparently. Blank lines are skipped. A malformed line is reported on
+// the error channel as ErrMalformedRecord with its line number and the stream continues;
+// a read or decompression failure is reported and ends the stream.
+//
+// Errors never hold up records: those the caller is not ready to receive are queued and
+// delivered after the pages channel is closed, so ranging over pages and then over errs
+// works, as does a select loop over both. At most 100 malformed-line errors are queued;
+// the rest are summed up in one final ErrMalformedRecord giving their count. The errs
+// channel is closed once every error has been received. The reading goroutine only exits
+// once both channels are drained; use ReadPageInfoStreamContext to stop it early
+func ReadPageInfoStream(r io.Reader) (<-chan *PageInfo, <-chan error) {
+	return ReadPageInfoStreamContext(context.Background(), r)
+}
+
+// ReadPageInfoStreamContext is like ReadPageInfoStream, but cancelling ctx stops the reader
+// and closes both channels, releasing the goroutine when the caller stops reading early
+func ReadPageInfoStreamContext(ctx context.Context, r io.Reader) (<-chan *PageInfo, <-chan error) {
+	pages := make(chan *PageInfo)
+	errs := make(chan error)
+
+	go func() {
+		var (
+			pending []error
+			dropped int
+		)
+		defer func() {
+			close(pages)
+			defer close(errs)
+			if dropped > 0 {
+				pending = append(pending, fmt.Errorf("%w: %d more malformed lines not reported", ErrMalformedRecord, dropped))
+			}
+			for _, err := range pending {
+				select {
+				case errs <- err:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		// report hands err to the caller if it is waiting for one and queues it otherwise.
+		// Malformed lines beyond the queue limit are only counted; stream failures end
+		// the stream and are always kept
+		report := func(err error) {
+			if errors.Is(err, ErrMalformedRecord) && len(pending) >= maxQueuedStreamErrors {
+				dropped++
+			} else {
+				pending = append(pending, err)
+			}
+			for len(pending) > 0 {
+				select {
+				case errs <- pending[0]:
+					pending = pending[1:]
+				default:
+					return
+				}
+			}
+		}
+
+		br := bufio.NewReader(r)
+		if magic, _ := br.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+			zr, err := gzip.NewReader(br)
+			if err != nil {
+				report(fmt.Errorf("failed to open gzip stream: %w", err))
+				return
+			}
+			defer zr.Close()
+			br = bufio.NewReader(zr)
+		}
+
+		for lineNo := 1; ; lineNo++ {
+			line, err := br.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				var info PageInfo
+				if decodeErr := json.Unmarshal(line, &info); decodeErr != nil {
+					report(fmt.Errorf("%w on line %d: %v", ErrMalformedRecord, lineNo, decodeErr))
+				} else {
+					select {
+					case pages <- &info:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				report(fmt.Errorf("failed to read stream: %w", err))
+				return
+			}
+		}
+	}()
+
+	return pages, errs
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// DuplicateParamMode controls how NormalizeURL treats repeated query keys such as ?a=1&a=2
+type DuplicateParamMode int
+
+const (
+	// KeepAllParams keeps every value of a repeated key, sorted (the default)
+	KeepAllParams DuplicateParamMode = iota
+	// KeepFirstParam keeps only the first value of a repeated key
+	KeepFirstParam
+	// KeepLastParam keeps only the last value of a repeated key
+	KeepLastParam
+)
+
+// NormalizeOption configures URL normalization
+type NormalizeOption func(*normalizeConfig)
+
+// normalizeConfig holds the settings used by NormalizeURL
+type normalizeConfig struct {
+	duplicates DuplicateParamMode
+}
+
+// WithDuplicateParams selects how repeated query keys are handled
+func WithDuplicateParams(mode DuplicateParamMode) NormalizeOption {
+	return func(cfg *normalizeConfig) {
+		cfg.duplicates = mode
+	}
+}
+
+// NormalizeURL returns a canonical form of rawURL suitable for deduplication.
+// The scheme and host are lowercased, default ports and fragments are removed,
+// an empty path becomes "/" and query keys are sorted. With KeepAllParams the values of
+// a repeated key are sorted too, so ?a=2&a=1 and ?a=1&a=2 normalize alike
+func NormalizeURL(rawURL string, opts ...NormalizeOption) (string, error) {
+	var cfg normalizeConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URL: %w", err)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	// Rebuild the query with sorted keys, resolving repeated keys per the configured mode
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse query: %w", err)
+	}
+	for key, values := range query {
+		switch cfg.duplicates {
+		case KeepAllParams:
+			slices.Sort(values)
+		case KeepFirstParam:
+			query[key] = values[:1]
+		case KeepLastParam:
+			query[key] = values[len(values)-1:]
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// extractNoscriptText returns the text of every <noscript> fallback in the document.
+// The parser runs with scripting enabled, so noscript content arrives as raw markup;
+// it is parsed as a fragment here to recover its text
+func extractNoscriptText(n *html.Node) string {
+	var parts []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "noscript" {
+			if text := noscriptText(n); text != "" {
+				parts = append(parts, text)
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(parts, " ")
+}
+
+// noscriptText parses the raw markup inside a <noscript> element and returns its text
+func noscriptText(n *html.Node) string {
+	var raw strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			raw.WriteString(c.Data)
+		}
+	}
+
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(raw.String()), body)
+	if err != nil {
+		return ""
+	}
+	var parts []string
+	for _, node := range nodes {
+		if text := textContent(node); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, " ")
+}
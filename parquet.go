@@ -0,0 +1,95 @@
+//go:build parquet
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRow is the columnar layout of a PageInfo.
+// Scalar fields map to columns directly, complex fields are stored as JSON strings.
+// word_count is the number of whitespace-separated words in the main content
+type parquetRow struct {
+	URL            string `parquet:"url"`
+	StatusCode     int32  `parquet:"status_code"`
+	Title          string `parquet:"title"`
+	Doctype        string `parquet:"doctype"`
+	QuirksMode     bool   `parquet:"quirks_mode"`
+	Canonical      string `parquet:"canonical"`
+	FaviconURL     string `parquet:"favicon_url"`
+	ManifestURL    string `parquet:"manifest_url"`
+	ContentHash    string `parquet:"content_hash"`
+	MainContent    string `parquet:"main_content"`
+	WordCount      int32  `parquet:"word_count"`
+	NumScripts     int32  `parquet:"num_scripts"`
+	NumStylesheets int32  `parquet:"num_stylesheets"`
+	NumImages      int32  `parquet:"num_images"`
+	NumIframes     int32  `parquet:"num_iframes"`
+	HashesJSON     string `parquet:"hashes_json"`
+	LinksJSON      string `parquet:"links_json"`
+	ImagesJSON     string `parquet:"images_json"`
+	PicturesJSON   string `parquet:"pictures_json"`
+}
+
+// WriteParquet writes the pages to w as a Parquet file with one row per page
+func WriteParquet(w io.Writer, infos []*PageInfo) error {
+	rows := make([]parquetRow, 0, len(infos))
+	for _, info := range infos {
+		row := parquetRow{
+			URL:            info.URL,
+			StatusCode:     int32(info.StatusCode),
+			Title:          info.Title,
+			Doctype:        info.Doctype,
+			QuirksMode:     info.QuirksMode,
+			Canonical:      info.Canonical,
+			FaviconURL:     info.FaviconURL,
+			ManifestURL:    info.ManifestURL,
+			ContentHash:    info.ContentHash,
+			MainContent:    info.MainContent,
+			WordCount:      int32(len(strings.Fields(info.MainContent))),
+			NumScripts:     int32(info.NumScripts),
+			NumStylesheets: int32(info.NumStylesheets),
+			NumImages:      int32(info.NumImages),
+			NumIframes:     int32(info.NumIframes),
+		}
+
+		// Encode complex fields as JSON strings
+		var err error
+		if row.HashesJSON, err = jsonString(info.Hashes); err != nil {
+			return err
+		}
+		if row.LinksJSON, err = jsonString(info.Links); err != nil {
+			return err
+		}
+		if row.ImagesJSON, err = jsonString(info.Images); err != nil {
+			return err
+		}
+		if row.PicturesJSON, err = jsonString(info.Pictures); err != nil {
+			return err
+		}
+		rows = append(rows, row)
+	}
+
+	pw := parquet.NewGenericWriter[parquetRow](w)
+	if _, err := pw.Write(rows); err != nil {
+		return fmt.Errorf("failed to write parquet rows: %w", err)
+	}
+	if err := pw.Close(); err != nil {
+		return fmt.Errorf("failed to finish parquet file: %w", err)
+	}
+	return nil
+}
+
+// jsonString encodes v as a JSON string column value
+func jsonString(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JSON column: %w", err)
+	}
+	return string(data), nil
+}
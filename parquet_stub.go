@@ -0,0 +1,17 @@
+//go:build !parquet
+
+package main
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrParquetUnsupported is returned by WriteParquet when built without the parquet tag
+var ErrParquetUnsupported = errors.New("parquet support not built in; rebuild with -tags parquet")
+
+// WriteParquet writes the pages to w as a Parquet file.
+// Parquet support is optional; build with -tags parquet to enable it
+func WriteParquet(w io.Writer, infos []*PageInfo) error {
+	return ErrParquetUnsupported
+}
@@ -0,0 +1,111 @@
+package main
+
+import "regexp"
+
+// PlatformSignal identifies which part of a PageInfo a platform rule inspects
+type PlatformSignal int
+
+const (
+	// GeneratorSignal matches the generator meta tag
+	GeneratorSignal PlatformSignal = iota
+	// AssetSignal matches script and stylesheet URLs
+	AssetSignal
+	// HeaderSignal matches the values of the rule's response header
+	HeaderSignal
+	// ClassSignal matches class names used in the document
+	ClassSignal
+)
+
+// PlatformRule is one piece of evidence for a platform.
+// When Pattern matches the signal, Weight is added to the platform's confidence
+type PlatformRule struct {
+	Platform string
+	Signal   PlatformSignal
+	Header   string // header name, for HeaderSignal
+	Pattern  *regexp.Regexp
+	Weight   float64
+}
+
+// PlatformRules is the rule set used by DetectPlatform. Append to it to recognize more platforms
+var PlatformRules = []PlatformRule{
+	{Platform: "WordPress", Signal: GeneratorSignal, Pattern: regexp.MustCompile(`(?i)^wordpress`), Weight: 0.9},
+	{Platform: "WordPress", Signal: AssetSignal, Pattern: regexp.MustCompile(`/wp-(content|includes)/`), Weight: 0.6},
+	{Platform: "WordPress", Signal: HeaderSignal, Header: "Link", Pattern: regexp.MustCompile(`/wp-json/`), Weight: 0.5},
+	{Platform: "WordPress", Signal: ClassSignal, Pattern: regexp.MustCompile(`^wp-`), Weight: 0.3},
+
+	{Platform: "Shopify", Signal: AssetSignal, Pattern: regexp.MustCompile(`cdn\.shopify\.com/`), Weight: 0.7},
+	{Platform: "Shopify", Signal: HeaderSignal, Header: "X-ShopId", Pattern: regexp.MustCompile(`.`), Weight: 0.8},
+	{Platform: "Shopify", Signal: HeaderSignal, Header: "Powered-By", Pattern: regexp.MustCompile(`(?i)shopify`), Weight: 0.8},
+	{Platform: "Shopify", Signal: ClassSignal, Pattern: regexp.MustCompile(`^shopify-`), Weight: 0.4},
+
+	{Platform: "Drupal", Signal: GeneratorSignal, Pattern: regexp.MustCompile(`(?i)^drupal`), Weight: 0.9},
+	{Platform: "Drupal", Signal: HeaderSignal, Header: "X-Generator", Pattern: regexp.MustCompile(`(?i)drupal`), Weight: 0.8},
+	{Platform: "Drupal", Signal: HeaderSignal, Header: "X-Drupal-Cache", Pattern: regexp.MustCompile(`.`), Weight: 0.7},
+	{Platform: "Drupal", Signal: AssetSignal, Pattern: regexp.MustCompile(`/sites/(all|default)/(themes|modules)/`), Weight: 0.5},
+
+	{Platform: "Joomla", Signal: GeneratorSignal, Pattern: regexp.MustCompile(`(?i)^joomla`), Weight: 0.9},
+	{Platform: "Joomla", Signal: AssetSignal, Pattern: regexp.MustCompile(`/media/(jui|system)/`), Weight: 0.5},
+
+	{Platform: "Wix", Signal: GeneratorSignal, Pattern: regexp.MustCompile(`(?i)^wix\.com`), Weight: 0.9},
+	{Platform: "Wix", Signal: HeaderSignal, Header: "X-Wix-Request-Id", Pattern: regexp.MustCompile(`.`), Weight: 0.8},
+	{Platform: "Wix", Signal: AssetSignal, Pattern: regexp.MustCompile(`static\.parastorage\.com/`), Weight: 0.6},
+
+	{Platform: "Squarespace", Signal: HeaderSignal, Header: "Server", Pattern: regexp.MustCompile(`(?i)squarespace`), Weight: 0.8},
+	{Platform: "Squarespace", Signal: AssetSignal, Pattern: regexp.MustCompile(`static1\.squarespace\.com/`), Weight: 0.6},
+
+	{Platform: "Ghost", Signal: GeneratorSignal, Pattern: regexp.MustCompile(`(?i)^ghost`), Weight: 0.9},
+	{Platform: "Hugo", Signal: GeneratorSignal, Pattern: regexp.MustCompile(`(?i)^hugo`), Weight: 0.9},
+	{Platform: "Gatsby", Signal: GeneratorSignal, Pattern: regexp.MustCompile(`(?i)^gatsby`), Weight: 0.9},
+
+	{Platform: "Next.js", Signal: HeaderSignal, Header: "X-Powered-By", Pattern: regexp.MustCompile(`(?i)next\.js`), Weight: 0.8},
+	{Platform: "Next.js", Signal: AssetSignal, Pattern: regexp.MustCompile(`/_next/static/`), Weight: 0.6},
+}
+
+// DetectPlatform guesses the CMS or site platform from an already populated PageInfo.
+// Each matching rule in PlatformRules adds its weight to its platform, capped at 1, and
+// the platform with the highest total is returned with that total as its confidence.
+// Ties go to the platform listed first. It returns "" and 0 if no rule matches
+func DetectPlatform(info *PageInfo) (platform string, confidence float64) {
+	scores := make(map[string]float64)
+	for _, rule := range PlatformRules {
+		if rule.matches(info) {
+			scores[rule.Platform] = min(scores[rule.Platform]+rule.Weight, 1)
+		}
+	}
+
+	// Walk the rules again so ties are broken by rule order
+	for _, rule := range PlatformRules {
+		if score := scores[rule.Platform]; score > confidence {
+			platform, confidence = rule.Platform, score
+		}
+	}
+	return platform, confidence
+}
+
+// matches reports whether the rule's pattern matches its signal in info
+func (r PlatformRule) matches(info *PageInfo) bool {
+	switch r.Signal {
+	case GeneratorSignal:
+		generator, ok := info.Meta["generator"]
+		return ok && r.Pattern.MatchString(generator)
+	case AssetSignal:
+		for _, asset := range info.Assets {
+			if r.Pattern.MatchString(asset.URL) {
+				return true
+			}
+		}
+	case HeaderSignal:
+		for _, value := range info.Headers.Values(r.Header) {
+			if r.Pattern.MatchString(value) {
+				return true
+			}
+		}
+	case ClassSignal:
+		for _, class := range info.ClassNames {
+			if r.Pattern.MatchString(class) {
+				return true
+			}
+		}
+	}
+	return false
+}
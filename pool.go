@@ -0,0 +1,37 @@
+package main
+
+import "context"
+
+// WorkerPool bounds the number of fetches running at once across every crawler sharing it.
+// It is safe for concurrent use and can be reused after the crawls using it finish
+type WorkerPool struct {
+	slots chan struct{}
+}
+
+// NewWorkerPool creates a pool allowing up to size concurrent fetches
+func NewWorkerPool(size int) *WorkerPool {
+	if size < 1 {
+		size = 1
+	}
+	return &WorkerPool{slots: make(chan struct{}, size)}
+}
+
+// Acquire blocks until a slot is free or ctx is done
+func (p *WorkerPool) Acquire(ctx context.Context) error {
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release returns a slot previously obtained with Acquire
+func (p *WorkerPool) Release() {
+	<-p.slots
+}
+
+// Size returns the maximum number of concurrent fetches
+func (p *WorkerPool) Size() int {
+	return cap(p.slots)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRedirects matches the redirect limit of the default http.Client
+const maxRedirects = 10
+
+// ErrRedirectBudgetExceeded is returned when following redirects takes longer than the configured budget
+var ErrRedirectBudgetExceeded = errors.New("redirect time budget exceeded")
+
+// ErrRedirectLoop is returned when a redirect leads back to a URL already in the chain
+var ErrRedirectLoop = errors.New("redirect loop detected")
+
+// RedirectLoopError lists the URLs forming a detected redirect loop, ending with the repeated URL
+type RedirectLoopError struct {
+	Loop []string
+}
+
+func (e *RedirectLoopError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrRedirectLoop, strings.Join(e.Loop, " -> "))
+}
+
+// Unwrap makes errors.Is(err, ErrRedirectLoop) match
+func (e *RedirectLoopError) Unwrap() error {
+	return ErrRedirectLoop
+}
+
+// RedirectHop records one redirect response that was followed
+type RedirectHop struct {
+	URL        string
+	StatusCode int
+	Latency    time.Duration
+}
+
+// redirectTrace records the redirect chain of a request as it is followed
+type redirectTrace struct {
+	mu        sync.Mutex
+	hopStart  time.Time
+	totalTime time.Duration
+	hops      []RedirectHop
+}
+
+// redirectTraceKey is the context key for a request's redirectTrace
+type redirectTraceKey struct{}
+
+// withRedirectTrace returns a context that records redirects into t
+func withRedirectTrace(ctx context.Context, t *redirectTrace) context.Context {
+	return context.WithValue(ctx, redirectTraceKey{}, t)
+}
+
+// redirectTraceFrom returns the redirectTrace stored in ctx, or nil
+func redirectTraceFrom(ctx context.Context) *redirectTrace {
+	t, _ := ctx.Value(redirectTraceKey{}).(*redirectTrace)
+	return t
+}
+
+// reset clears the trace at the start of a new attempt
+func (t *redirectTrace) reset(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.hopStart = now
+	t.totalTime = 0
+	t.hops = nil
+}
+
+// record adds a hop that ended at now and returns the cumulative redirect time
+func (t *redirectTrace) record(url string, statusCode int, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	latency := now.Sub(t.hopStart)
+	t.hopStart = now
+	t.totalTime += latency
+	t.hops = append(t.hops, RedirectHop{URL: url, StatusCode: statusCode, Latency: latency})
+	return t.totalTime
+}
+
+// list returns a copy of the recorded hops
+func (t *redirectTrace) list() []RedirectHop {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]RedirectHop(nil), t.hops...)
+}
+
+// WithRedirectBudget aborts a request with ErrRedirectBudgetExceeded once the time spent
+// receiving redirect responses exceeds budget. Time spent on the final response is not counted.
+// Since the check runs as each redirect arrives, a single hop that never responds is
+// bounded by the request timeout rather than the budget
+func WithRedirectBudget(budget time.Duration) ClientOption {
+	return func(c *HTTPClient) {
+		c.redirectBudget = budget
+	}
+}
+
+// WithRedirectLoopDetection controls whether a redirect back to a URL already in the
+// chain fails immediately with a RedirectLoopError. It is enabled by default; disable it
+// for sites that legitimately redirect to the same URL, e.g. after setting a cookie
+func WithRedirectLoopDetection(enabled bool) ClientOption {
+	return func(c *HTTPClient) {
+		c.allowRedirectLoops = !enabled
+	}
+}
+
+// checkRedirect records each redirect hop and enforces the redirect limits
+func (c *HTTPClient) checkRedirect(req *http.Request, via []*http.Request) error {
+	if !c.allowRedirectLoops {
+		if loop := findRedirectLoop(req, via); loop != nil {
+			return &RedirectLoopError{Loop: loop}
+		}
+	}
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+
+	t := redirectTraceFrom(req.Context())
+	if t == nil || req.Response == nil {
+		return nil
+	}
+	elapsed := t.record(via[len(via)-1].URL.String(), req.Response.StatusCode, time.Now())
+	if c.redirectBudget > 0 && elapsed > c.redirectBudget {
+		return fmt.Errorf("%w: %v spent on %d redirects (budget %v)", ErrRedirectBudgetExceeded, elapsed, len(via), c.redirectBudget)
+	}
+	return nil
+}
+
+// findRedirectLoop returns the URLs from the earlier visit of req's URL up to req,
+// or nil if req's URL does not already appear in the chain
+func findRedirectLoop(req *http.Request, via []*http.Request) []string {
+	target := redirectKey(req.URL.String())
+	for i, prev := range via {
+		if redirectKey(prev.URL.String()) != target {
+			continue
+		}
+		loop := make([]string, 0, len(via)-i+1)
+		for _, r := range via[i:] {
+			loop = append(loop, r.URL.String())
+		}
+		return append(loop, req.URL.String())
+	}
+	return nil
+}
+
+// redirectKey normalizes a URL for loop comparison
+func redirectKey(rawURL string) string {
+	if normalized, err := NormalizeURL(rawURL); err == nil {
+		return normalized
+	}
+	return rawURL
+}
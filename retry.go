@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultRetryBaseDelay is the first backoff delay of the built-in retry policy
+const defaultRetryBaseDelay = 500 * time.Millisecond
+
+// defaultRetryMaxDelay caps the computed backoff of the built-in retry policy
+const defaultRetryMaxDelay = 30 * time.Second
+
+// ShouldRetryFunc decides, after each attempt, whether a request is retried and how long
+// to wait before the next attempt. attempt counts the attempts made so far, starting at 1
+type ShouldRetryFunc func(resp *http.Response, err error, attempt int) (retry bool, delay time.Duration)
+
+// WithRetries retries idempotent requests that fail with a network error or a 5xx
+// response up to maxRetries times. It is shorthand for WithRetryConfig with a
+// RetryableStatus matching every 5xx status
+func WithRetries(maxRetries int) ClientOption {
+	return WithRetryConfig(RetryConfig{
+		MaxRetries:      maxRetries,
+		RetryableStatus: func(status int) bool { return status >= 500 },
+	})
+}
+
+// WithShouldRetry replaces the retry policy with fn, which then fully controls
+// retry decisions. fn must eventually return false, as no attempt limit is applied
+func WithShouldRetry(fn ShouldRetryFunc) ClientOption {
+	return func(c *HTTPClient) {
+		c.shouldRetry = fn
+		c.idempotentRetries = false
+	}
+}
+
+// RetryConfig configures the retry policy installed by WithRetryConfig
+type RetryConfig struct {
+	// MaxRetries is the number of retries after the first attempt; zero disables retries
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry, doubled for each later one.
+	// Zero uses 500ms
+	BaseDelay time.Duration
+	// MaxDelay caps the computed backoff before jitter is added. Zero uses 30s.
+	// A Retry-After header is honoured even when it asks for longer
+	MaxDelay time.Duration
+	// RetryableStatus reports whether a response status is worth retrying.
+	// Nil retries 429, 500, 502, 503 and 504
+	RetryableStatus func(status int) bool
+}
+
+// WithRetryConfig retries idempotent requests (GET, HEAD, OPTIONS, PUT and DELETE) that
+// fail with a network error or a retryable status. Delays grow exponentially from
+// BaseDelay up to MaxDelay with up to 50% random jitter, and a Retry-After header, in seconds or as an
+// HTTP date, overrides the computed delay. As with every retry policy, each attempt waits
+// for the rate limiter and backoff sleeps end as soon as the request context is done
+func WithRetryConfig(cfg RetryConfig) ClientOption {
+	return func(c *HTTPClient) {
+		c.shouldRetry = cfg.policy()
+		c.idempotentRetries = true
+	}
+}
+
+// policy builds the ShouldRetryFunc described by the config
+func (cfg RetryConfig) policy() ShouldRetryFunc {
+	baseDelay := cfg.BaseDelay
+	if baseDelay <= 0 {
+		baseDelay = defaultRetryBaseDelay
+	}
+	maxDelay := cfg.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+	baseDelay = min(baseDelay, maxDelay)
+	retryable := cfg.RetryableStatus
+	if retryable == nil {
+		retryable = defaultRetryableStatus
+	}
+
+	return func(resp *http.Response, err error, attempt int) (bool, time.Duration) {
+		if attempt > cfg.MaxRetries || isPermanentError(err) {
+			return false, 0
+		}
+		if err == nil && !retryable(resp.StatusCode) {
+			return false, 0
+		}
+		if resp != nil {
+			if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				return true, delay
+			}
+		}
+		return true, backoff(baseDelay, maxDelay, attempt)
+	}
+}
+
+// backoff returns the delay before retry number attempt: baseDelay doubled for each earlier
+// retry and capped at maxDelay, plus up to 50% random jitter. Doubling stops at the cap,
+// so large attempt counts cannot overflow
+func backoff(baseDelay, maxDelay time.Duration, attempt int) time.Duration {
+	delay := baseDelay
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		if delay > maxDelay/2 {
+			delay = maxDelay
+		} else {
+			delay *= 2
+		}
+	}
+	return delay + rand.N(delay/2+1)
+}
+
+// defaultRetryableStatus reports whether a status usually indicates a transient failure
+func defaultRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter parses a Retry-After value given as delay seconds or an HTTP date.
+// Dates in the past yield a zero delay
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// isIdempotent reports whether a request with method can safely be sent more than once
+func isIdempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isPermanentError reports whether err is a failure that retrying cannot fix
+func isPermanentError(err error) bool {
+	return errors.Is(err, ErrRedirectBudgetExceeded) || errors.Is(err, ErrRedirectLoop)
+}
+
+// doWithRetries sends req, retrying according to the client's retry policy.
+// Every attempt waits for the rate limiter, and backoff sleeps end early if the request context is done
+func (c *HTTPClient) doWithRetries(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		// Wait for rate limiter permission
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("rate limiter error: %w", err)
+		}
+
+		// Perform the request
+		resp, err := c.send(req)
+		if err == nil && isWebSocketResponse(resp) {
+			// Close the connection rather than trying to read it as a document
+			resp.Body.Close()
+			return nil, fmt.Errorf("%w: %s", ErrWebSocketEndpoint, req.URL)
+		}
+		if c.shouldRetry == nil || ctx.Err() != nil {
+			return resp, err
+		}
+		if c.idempotentRetries && !isIdempotent(req.Method) {
+			return resp, err
+		}
+		retry, delay := c.shouldRetry(resp, err, attempt)
+		if !retry {
+			return resp, err
+		}
+
+		if !replayable(req) {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+
+		if req, err = rewindRequest(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// replayable reports whether req can be sent again. A consumed body can only be replayed
+// if the request knows how to recreate it
+func replayable(req *http.Request) bool {
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	return !hasBody || req.GetBody != nil
+}
+
+// rewindRequest returns a copy of a replayable req with a fresh body, ready to be sent again
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	retry := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewind request body: %w", err)
+		}
+		retry.Body = body
+	}
+	return retry, nil
+}
+
+// sleepContext waits for d, returning early with the context error if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
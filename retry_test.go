@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// flakyServer answers 503 to the first failures requests, sending retryAfter as the
+// Retry-After header when set, and 200 afterwards. The returned counter holds the number
+// of requests received
+func flakyServer(t *testing.T, failures int, retryAfter string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(hits.Add(1)) <= failures {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func TestRetryConfigSucceedsAfterTransientFailures(t *testing.T) {
+	srv, hits := flakyServer(t, 2, "")
+	client := NewHTTPClient(rate.Inf, 1, WithRetryConfig(RetryConfig{MaxRetries: 3, BaseDelay: time.Millisecond}))
+
+	resp, err := client.Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if got := hits.Load(); got != 3 {
+		t.Errorf("attempts = %d, want 3", got)
+	}
+}
+
+func TestRetryConfigGivesUpAfterMaxRetries(t *testing.T) {
+	srv, hits := flakyServer(t, 5, "")
+	client := NewHTTPClient(rate.Inf, 1, WithRetryConfig(RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond}))
+
+	resp, err := client.Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	if got := hits.Load(); got != 3 {
+		t.Errorf("attempts = %d, want 3", got)
+	}
+}
+
+func TestRetryConfigHonoursRetryAfter(t *testing.T) {
+	srv, hits := flakyServer(t, 1, "2")
+	client := NewHTTPClient(rate.Inf, 1, WithRetryConfig(RetryConfig{MaxRetries: 1, BaseDelay: time.Millisecond}))
+
+	start := time.Now()
+	resp, err := client.Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+
+	if elapsed := time.Since(start); elapsed < 2*time.Second {
+		t.Errorf("retried after %v, want at least the 2s Retry-After delay", elapsed)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Errorf("attempts = %d, want 2", got)
+	}
+}
+
+func TestRetryConfigBackoffEndsOnCancel(t *testing.T) {
+	srv, hits := flakyServer(t, 5, "60")
+	client := NewHTTPClient(rate.Inf, 1, WithRetryConfig(RetryConfig{MaxRetries: 3}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := client.Get(ctx, srv.URL)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Get returned after %v, want the backoff sleep to end on cancel", elapsed)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("attempts = %d, want 1", got)
+	}
+}
+
+func TestRetryConfigSkipsNonIdempotentRequests(t *testing.T) {
+	srv, hits := flakyServer(t, 2, "")
+	client := NewHTTPClient(rate.Inf, 1, WithRetries(3))
+
+	resp, err := client.Post(context.Background(), srv.URL, "text/plain", strings.NewReader("body"))
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("attempts = %d, want 1", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"2", 2 * time.Second, true},
+		{" 0 ", 0, true},
+		{"-1", 0, false},
+		{"", 0, false},
+		{"soon", 0, false},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBackoffIsCappedForLargeAttempts(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 500 * time.Millisecond},
+		{2, time.Second},
+		{6, 16 * time.Second},
+		{7, 30 * time.Second},
+		{35, 30 * time.Second},
+		{36, 30 * time.Second},
+		{1000, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		// Jitter adds up to half the delay on top
+		got := backoff(500*time.Millisecond, 30*time.Second, tt.attempt)
+		if got < tt.want || got > tt.want+tt.want/2 {
+			t.Errorf("backoff(attempt %d) = %v, want between %v and %v", tt.attempt, got, tt.want, tt.want+tt.want/2)
+		}
+	}
+}
+
+func TestRetryConfigLargeMaxRetries(t *testing.T) {
+	srv, hits := flakyServer(t, 1<<30, "")
+	client := NewHTTPClient(rate.Inf, 1, WithRetryConfig(RetryConfig{
+		MaxRetries: 100,
+		BaseDelay:  time.Microsecond,
+		MaxDelay:   time.Millisecond,
+	}))
+
+	start := time.Now()
+	resp, err := client.Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := hits.Load(); got != 101 {
+		t.Errorf("attempts = %d, want 101", got)
+	}
+	// 100 retries at no less than the 1ms cap from attempt 11 on
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("100 retries took %v, want the backoff to stay at the cap rather than wrap around", elapsed)
+	}
+}
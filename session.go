@@ -0,0 +1,50 @@
+package main
+
+import "net/http"
+
+// Session sends requests over a single dedicated connection per host, for APIs that tie
+// quotas or state to connection identity. It shares the parent client's rate limiter,
+// retry policy and hooks, but uses its own transport limited to one connection per host
+// with no idle timeout.
+//
+// Tradeoffs: requests made concurrently through a session are serialized, since they
+// wait for the one connection; and if the server or a proxy closes the connection,
+// the next request transparently opens a new one, so pinning is best effort.
+// A client using a custom RoundTripper that is not an *http.Transport cannot be pinned;
+// its sessions send requests through that RoundTripper unchanged.
+// Call Close when done to release the connection
+type Session struct {
+	*HTTPClient
+	transport *http.Transport
+}
+
+// NewSession creates a session derived from the client. The session's transport is a
+// clone of the client's, so TLS and header limit options carry over
+func (c *HTTPClient) NewSession() *Session {
+	sc := *c
+	sc.client = &http.Client{
+		Transport:     c.client.Transport,
+		CheckRedirect: c.client.CheckRedirect,
+		Jar:           c.client.Jar,
+		Timeout:       c.client.Timeout,
+	}
+
+	t, ok := c.client.Transport.(*http.Transport)
+	if !ok {
+		return &Session{HTTPClient: &sc}
+	}
+	transport := t.Clone()
+	transport.MaxConnsPerHost = 1
+	transport.MaxIdleConnsPerHost = 1
+	transport.IdleConnTimeout = 0
+	sc.client.Transport = transport
+	sc.transport = transport
+	return &Session{HTTPClient: &sc, transport: transport}
+}
+
+// Close closes the session's idle connection
+func (s *Session) Close() {
+	if s.transport != nil {
+		s.transport.CloseIdleConnections()
+	}
+}
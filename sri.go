@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// maxSubresourceBytes caps the size of a subresource downloaded for integrity checking
+const maxSubresourceBytes = 10 << 20
+
+var (
+	// ErrIntegrityMismatch is reported when a subresource does not match its integrity attribute
+	ErrIntegrityMismatch = errors.New("integrity mismatch")
+	// ErrNoSupportedIntegrity is reported for integrity attributes without a sha256, sha384 or sha512 hash
+	ErrNoSupportedIntegrity = errors.New("no supported integrity hash")
+)
+
+// sriAlgorithms lists the supported integrity algorithms from weakest to strongest
+var sriAlgorithms = []struct {
+	name string
+	new  func() hash.Hash
+}{
+	{"sha256", sha256.New},
+	{"sha384", sha512.New384},
+	{"sha512", sha512.New},
+}
+
+// verifyIntegrity checks data against an integrity attribute value. As the SRI specification
+// requires, only the hashes of the strongest algorithm listed are used, and any of them may match
+func verifyIntegrity(data []byte, integrity string) error {
+	strongest := -1
+	var digests []string
+	for _, token := range strings.Fields(integrity) {
+		// Drop options such as ?ct=... after the digest
+		token, _, _ = strings.Cut(token, "?")
+		alg, digest, ok := strings.Cut(token, "-")
+		if !ok {
+			continue
+		}
+		for i, a := range sriAlgorithms {
+			if !strings.EqualFold(alg, a.name) || i < strongest {
+				continue
+			}
+			if i > strongest {
+				strongest, digests = i, nil
+			}
+			digests = append(digests, digest)
+		}
+	}
+	if strongest < 0 {
+		return ErrNoSupportedIntegrity
+	}
+
+	alg := sriAlgorithms[strongest]
+	h := alg.new()
+	h.Write(data)
+	actual := base64.StdEncoding.EncodeToString(h.Sum(nil))
+	for _, digest := range digests {
+		if subtle.ConstantTimeCompare([]byte(actual), []byte(digest)) == 1 {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s digest is %s", ErrIntegrityMismatch, alg.name, actual)
+}
+
+// checkSubresources fetches every asset that declares an integrity attribute and verifies it,
+// returning one message per asset that could not be fetched or did not match
+func checkSubresources(ctx context.Context, httpClient *HTTPClient, assets []Asset) []string {
+	var failures []string
+	for _, asset := range assets {
+		if asset.Integrity == "" {
+			continue
+		}
+		if err := checkSubresource(ctx, httpClient, asset); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", asset.URL, err))
+		}
+	}
+	return failures
+}
+
+// checkSubresource fetches one asset through the rate-limited client and verifies its integrity
+func checkSubresource(ctx context.Context, httpClient *HTTPClient, asset Asset) error {
+	resp, err := httpClient.Get(ctx, asset.URL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch subresource: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSubresourceBytes))
+	if err != nil {
+		return fmt.Errorf("failed to read subresource: %w", err)
+	}
+	return verifyIntegrity(data, asset.Integrity)
+}
@@ -0,0 +1,35 @@
+package main
+
+import "sync"
+
+// ResultStore receives each page as soon as the crawler finishes it.
+// Put may be called concurrently from several crawl workers
+type ResultStore interface {
+	Put(info *PageInfo) error
+}
+
+// MemoryStore is a ResultStore that keeps pages in memory
+type MemoryStore struct {
+	mu    sync.Mutex
+	pages []*PageInfo
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Put appends the page to the store
+func (s *MemoryStore) Put(info *PageInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pages = append(s.pages, info)
+	return nil
+}
+
+// Pages returns the stored pages in the order they were put
+func (s *MemoryStore) Pages() []*PageInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*PageInfo(nil), s.pages...)
+}
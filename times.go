@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// TimeMention is a <time> element found on a page
+type TimeMention struct {
+	Raw    string
+	Parsed *time.Time
+	Text   string
+}
+
+// timeLayouts are the HTML date and datetime formats tried when parsing <time> values
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999-0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// extractTimes collects every <time> element. The machine-readable value comes from the
+// datetime attribute, or from the element's text when the attribute is absent, as in HTML.
+// Parsed is nil when the value matches none of the supported formats
+func extractTimes(n *html.Node) []TimeMention {
+	var times []TimeMention
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "time" {
+			text := textContent(n)
+			raw := strings.TrimSpace(getAttr(n, "datetime"))
+			if raw == "" {
+				raw = text
+			}
+			times = append(times, TimeMention{
+				Raw:    raw,
+				Parsed: parseHTMLTime(raw),
+				Text:   text,
+			})
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return times
+}
+
+// parseHTMLTime parses a date or datetime string in one of the supported layouts
+func parseHTMLTime(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
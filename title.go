@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidTitleUTF8 is returned for titles with invalid UTF-8 under RejectInvalidUTF8
+var ErrInvalidTitleUTF8 = errors.New("title contains invalid UTF-8")
+
+// InvalidUTF8Mode controls how invalid UTF-8 in page titles is handled. Replacement
+// characters (U+FFFD) already present in the title count as invalid in every mode
+// but ReplaceInvalidUTF8, since they usually come from an earlier failed decode
+type InvalidUTF8Mode int
+
+const (
+	// ReplaceInvalidUTF8 replaces each invalid byte sequence with U+FFFD (the default)
+	ReplaceInvalidUTF8 InvalidUTF8Mode = iota
+	// StripInvalidUTF8 removes invalid byte sequences and replacement characters
+	StripInvalidUTF8
+	// RejectInvalidUTF8 fails the fetch with ErrInvalidTitleUTF8
+	RejectInvalidUTF8
+)
+
+// sanitizeTitle applies the invalid UTF-8 mode to an extracted title
+func sanitizeTitle(title string, mode InvalidUTF8Mode) (string, error) {
+	switch mode {
+	case StripInvalidUTF8:
+		title = strings.ToValidUTF8(title, "")
+		return strings.TrimSpace(strings.ReplaceAll(title, string(utf8.RuneError), "")), nil
+	case RejectInvalidUTF8:
+		if !utf8.ValidString(title) || strings.ContainsRune(title, utf8.RuneError) {
+			return "", fmt.Errorf("%w: %q", ErrInvalidTitleUTF8, title)
+		}
+		return title, nil
+	default:
+		return strings.ToValidUTF8(title, string(utf8.RuneError)), nil
+	}
+}
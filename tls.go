@@ -0,0 +1,36 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+)
+
+// WithRootCAs makes the client trust the certificate authorities in pool instead of the
+// system roots, for example an internal CA. Standard chain and hostname verification still
+// apply; only the set of trusted roots changes. See WithTransport for custom transports
+func WithRootCAs(pool *x509.CertPool) ClientOption {
+	return func(c *HTTPClient) {
+		c.transportOpts.rootCAs = pool
+	}
+}
+
+// WithVerifyPeerCertificate adds a custom check of the server's certificates, such as
+// pinning or requiring a specific issuer. fn runs after the standard verification against
+// the trusted roots has succeeded and receives the raw certificates and the verified
+// chains; returning an error aborts the handshake. It cannot relax the standard checks.
+// fn is not called again for resumed TLS sessions, which were verified on first use.
+// See WithTransport for custom transports
+func WithVerifyPeerCertificate(fn func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error) ClientOption {
+	return func(c *HTTPClient) {
+		c.transportOpts.verifyPeer = fn
+	}
+}
+
+// tlsConfig returns the transport's TLS config, creating it if needed
+func tlsConfig(t *http.Transport) *tls.Config {
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	return t.TLSClientConfig
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// extractWarnings reports ambiguous or missing-but-expected data found in the document,
+// such as a missing title, conflicting canonical links or a charset that disagrees with
+// the Content-Type header
+func extractWarnings(doc *html.Node, header http.Header) []string {
+	var (
+		warnings   []string
+		titles     int
+		emptyTitle bool
+		canonicals = newOrderedSet()
+		bases      int
+		charsets   = newOrderedSet()
+		meta       = make(map[string]string)
+	)
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "title":
+				titles++
+				if textContent(n) == "" {
+					emptyTitle = true
+				}
+			case "base":
+				if hasAttr(n, "href") {
+					bases++
+				}
+			case "link":
+				if hasRel(n, "canonical") {
+					canonicals.add(strings.TrimSpace(getAttr(n, "href")))
+				}
+			case "meta":
+				if charset := strings.TrimSpace(getAttr(n, "charset")); charset != "" {
+					charsets.add(strings.ToLower(charset))
+				}
+				if strings.EqualFold(getAttr(n, "http-equiv"), "content-type") {
+					if charset := contentTypeCharset(getAttr(n, "content")); charset != "" {
+						charsets.add(charset)
+					}
+				}
+				warnings = append(warnings, checkMeta(n, meta)...)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	switch {
+	case titles == 0:
+		warnings = append(warnings, "no title element found")
+	case titles > 1:
+		warnings = append(warnings, fmt.Sprintf("%d title elements, only the first is used", titles))
+	case emptyTitle:
+		warnings = append(warnings, "title element is empty")
+	}
+	if len(canonicals.list()) > 1 {
+		warnings = append(warnings, fmt.Sprintf("multiple canonical links: %s", strings.Join(canonicals.list(), ", ")))
+	}
+	if bases > 1 {
+		warnings = append(warnings, fmt.Sprintf("%d base elements, only the first is used", bases))
+	}
+
+	declared := charsets.list()
+	if len(declared) > 1 {
+		warnings = append(warnings, fmt.Sprintf("conflicting meta charsets: %s", strings.Join(declared, ", ")))
+	}
+	if headerCharset := contentTypeCharset(header.Get("Content-Type")); headerCharset != "" && len(declared) > 0 {
+		if !strings.EqualFold(declared[0], headerCharset) {
+			warnings = append(warnings, fmt.Sprintf("meta charset %q conflicts with header charset %q", declared[0], headerCharset))
+		}
+	}
+	return warnings
+}
+
+// checkMeta reports a malformed <meta> name/property tag, or one that repeats an earlier
+// key with a different value. seen records the first value of each key
+func checkMeta(n *html.Node, seen map[string]string) []string {
+	key := getAttr(n, "name")
+	if key == "" {
+		key = getAttr(n, "property")
+	}
+	key = strings.ToLower(strings.TrimSpace(key))
+	if key == "" {
+		return nil
+	}
+	if !hasAttr(n, "content") {
+		return []string{fmt.Sprintf("meta %q has no content attribute", key)}
+	}
+
+	content := strings.TrimSpace(getAttr(n, "content"))
+	first, exists := seen[key]
+	if !exists {
+		seen[key] = content
+		return nil
+	}
+	if first != content {
+		return []string{fmt.Sprintf("duplicate meta %q with different values, the first is used", key)}
+	}
+	return nil
+}
+
+// contentTypeCharset returns the lowercased charset parameter of a Content-Type value, or ""
+func contentTypeCharset(contentType string) string {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(params["charset"]))
+}
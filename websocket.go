@@ -0,0 +1,29 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrWebSocketEndpoint is returned for URLs that serve a WebSocket rather than a regular HTTP resource
+var ErrWebSocketEndpoint = errors.New("URL is a WebSocket endpoint")
+
+// isWebSocketURL reports whether the URL uses a WebSocket scheme
+func isWebSocketURL(req *http.Request) bool {
+	return req.URL.Scheme == "ws" || req.URL.Scheme == "wss"
+}
+
+// isWebSocketResponse reports whether a response switches to, or asks the client to
+// upgrade to, the WebSocket protocol instead of serving content
+func isWebSocketResponse(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		return true
+	}
+	for _, upgrade := range resp.Header.Values("Upgrade") {
+		if strings.Contains(strings.ToLower(upgrade), "websocket") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultTopWords is the number of words kept in PageInfo.TopWords unless FetchOptions says otherwise
+const defaultTopWords = 20
+
+// WordCount is a word and the number of times it occurs
+type WordCount struct {
+	Word  string
+	Count int
+}
+
+// DefaultStopwords are the common English words left out of word frequencies
+var DefaultStopwords = map[string]bool{
+	"a": true, "about": true, "after": true, "all": true, "also": true, "an": true, "and": true,
+	"any": true, "are": true, "as": true, "at": true, "be": true, "been": true, "but": true,
+	"by": true, "can": true, "could": true, "did": true, "do": true, "does": true, "for": true,
+	"from": true, "had": true, "has": true, "have": true, "he": true, "her": true, "his": true,
+	"how": true, "i": true, "if": true, "in": true, "into": true, "is": true, "it": true,
+	"its": true, "it's": true, "just": true, "more": true, "most": true, "my": true, "no": true,
+	"not": true, "of": true, "on": true, "one": true, "or": true, "our": true, "out": true,
+	"she": true, "so": true, "some": true, "than": true, "that": true, "the": true, "their": true,
+	"them": true, "then": true, "there": true, "these": true, "they": true, "this": true,
+	"to": true, "up": true, "us": true, "was": true, "we": true, "were": true, "what": true,
+	"when": true, "which": true, "who": true, "will": true, "with": true, "would": true,
+	"you": true, "your": true,
+}
+
+// extractWordFrequencies returns the topN most frequent words in text, most frequent first
+// with ties in alphabetical order. Words are lowercased and stripped of surrounding
+// punctuation; single characters and stopwords are skipped
+func extractWordFrequencies(text string, topN int, stopwords map[string]bool) []WordCount {
+	counts := make(map[string]int)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '\''
+	})
+	for _, word := range words {
+		word = strings.Trim(word, "'")
+		if utf8.RuneCountInString(word) < 2 || stopwords[word] {
+			continue
+		}
+		counts[word]++
+	}
+
+	freqs := make([]WordCount, 0, len(counts))
+	for word, count := range counts {
+		freqs = append(freqs, WordCount{Word: word, Count: count})
+	}
+	slices.SortFunc(freqs, func(a, b WordCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Word, b.Word))
+	})
+	if len(freqs) > topN {
+		freqs = freqs[:topN]
+	}
+	return freqs
+}